@@ -0,0 +1,66 @@
+package authn
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+var _ SchemeAuthenticator = (*APIKeyAuthenticator)(nil)
+
+// APIKeyAuthenticator verifies static API keys, sent as "ApiKey <key>". Keys
+// are kept as SHA-256 hashes, so that the plain keys need not be deployed.
+type APIKeyAuthenticator struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	hash      []byte
+	principal Principal
+}
+
+func NewAPIKeyAuthenticator() *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{}
+}
+
+// WithKey registers a plain API key.
+func (a *APIKeyAuthenticator) WithKey(key string, principal Principal) *APIKeyAuthenticator {
+	hash := sha256.Sum256([]byte(key))
+	a.keys = append(a.keys, apiKey{hash: hash[:], principal: principal})
+	return a
+}
+
+// WithHashedKey registers an API key by its hex-encoded SHA-256 hash.
+func (a *APIKeyAuthenticator) WithHashedKey(hexHash string, principal Principal) *APIKeyAuthenticator {
+	hash, err := hex.DecodeString(strings.TrimSpace(hexHash))
+	if err != nil || len(hash) != sha256.Size {
+		panic("invalid sha256 hash of api key")
+	}
+
+	a.keys = append(a.keys, apiKey{hash: hash, principal: principal})
+	return a
+}
+
+func (a *APIKeyAuthenticator) Scheme() string {
+	return "ApiKey"
+}
+
+func (a *APIKeyAuthenticator) Verify(ctx context.Context, credentials string) (*Principal, error) {
+	hash := sha256.Sum256([]byte(credentials))
+
+	var matched *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(a.keys[i].hash, hash[:]) == 1 {
+			matched = &a.keys[i]
+		}
+	}
+
+	if matched == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal := matched.principal
+	return &principal, nil
+}
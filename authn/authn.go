@@ -0,0 +1,163 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/todennus/x/scope"
+	"github.com/xybor-x/snowflake"
+)
+
+var (
+	ErrMalformed          = errors.New("malformed authorization")
+	ErrUnsupportedScheme  = errors.New("unsupported authorization scheme")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrExpiredCredentials = errors.New("expired credentials")
+)
+
+// Kind is the kind of subject of a principal. Kinds can be combined to
+// describe the principals accepted by an API.
+type Kind int
+
+const (
+	KindUser Kind = 1 << iota
+	KindClient
+
+	KindAny = KindUser | KindClient
+)
+
+func (kind Kind) String() string {
+	switch kind {
+	case KindUser:
+		return "user"
+	case KindClient:
+		return "client"
+	case KindAny:
+		return "any"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseKind returns the kind named by Kind.String, e.g. "user".
+func ParseKind(name string) (Kind, error) {
+	for _, kind := range []Kind{KindUser, KindClient, KindAny} {
+		if kind.String() == name {
+			return kind, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown principal kind %q", name)
+}
+
+// Principal is the identity behind an authenticated request.
+type Principal struct {
+	// UserID is the subject of the request, zero if the request is made by a
+	// client on its own behalf.
+	UserID snowflake.ID
+
+	// ClientID is the OAuth2 client which makes the request, empty if it is
+	// unknown.
+	ClientID string
+
+	Scopes scope.Scopes
+
+	// TokenID, ExpiresAt and AuthTime describe the access token of the
+	// request. They are empty for credentials which are not tokens.
+	TokenID   string
+	ExpiresAt time.Time
+	AuthTime  time.Time
+}
+
+// Kind returns KindUser if the request is made on behalf of a user, or
+// KindClient if it is made by a client on its own behalf, e.g. with a
+// client_credentials token.
+func (p *Principal) Kind() Kind {
+	if p.UserID != 0 {
+		return KindUser
+	}
+
+	return KindClient
+}
+
+// Authenticator verifies a full authorization header or authorization
+// metadata, i.e. "<scheme> <credentials>".
+type Authenticator interface {
+	// Authenticate returns the principal owning the authorization. It returns
+	// ErrMalformed or ErrUnsupportedScheme if the authorization cannot be
+	// handled, ErrInvalidCredentials or ErrExpiredCredentials if the
+	// credentials are rejected.
+	Authenticate(ctx context.Context, authorization string) (*Principal, error)
+}
+
+// SchemeAuthenticator verifies the credentials of a single authorization
+// scheme, without the scheme prefix. It is plugged into a Chain, which is the
+// Authenticator used by the middlewares and interceptors.
+type SchemeAuthenticator interface {
+	// Scheme is the authorization scheme handled by the authenticator, it is
+	// compared case-insensitively.
+	Scheme() string
+
+	// Verify returns the principal owning the credentials. It returns
+	// ErrInvalidCredentials or ErrExpiredCredentials if they are rejected.
+	Verify(ctx context.Context, credentials string) (*Principal, error)
+}
+
+var _ Authenticator = (*Chain)(nil)
+
+// Chain tries its authenticators in order. Only the authenticators handling
+// the scheme of the authorization are tried, the first accepting the
+// credentials wins.
+type Chain struct {
+	authenticators []SchemeAuthenticator
+}
+
+func NewChain(authenticators ...SchemeAuthenticator) *Chain {
+	return &Chain{authenticators: authenticators}
+}
+
+// With appends authenticators to the chain.
+func (c *Chain) With(authenticators ...SchemeAuthenticator) *Chain {
+	c.authenticators = append(c.authenticators, authenticators...)
+	return c
+}
+
+// Authenticate verifies a full authorization value, e.g. "Bearer <token>".
+func (c *Chain) Authenticate(ctx context.Context, authorization string) (*Principal, error) {
+	scheme, credentials, found := strings.Cut(authorization, " ")
+	if !found || credentials == "" {
+		return nil, ErrMalformed
+	}
+
+	err := ErrUnsupportedScheme
+	for _, authenticator := range c.authenticators {
+		if !strings.EqualFold(authenticator.Scheme(), scheme) {
+			continue
+		}
+
+		var principal *Principal
+		principal, err = authenticator.Verify(ctx, credentials)
+		if err == nil {
+			return principal, nil
+		}
+	}
+
+	return nil, err
+}
+
+// Reason returns a short label of an authentication error, e.g. for metrics.
+func Reason(err error) string {
+	switch {
+	case errors.Is(err, ErrMalformed):
+		return "malformed"
+	case errors.Is(err, ErrUnsupportedScheme):
+		return "unsupported_type"
+	case errors.Is(err, ErrExpiredCredentials):
+		return "expired_token"
+	default:
+		return "invalid_token"
+	}
+}
@@ -0,0 +1,270 @@
+package authn_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/sharedtest"
+	"github.com/todennus/x/scope"
+)
+
+// stubAuthenticator accepts a single credentials.
+type stubAuthenticator struct {
+	scheme      string
+	credentials string
+	principal   authn.Principal
+}
+
+func (a stubAuthenticator) Scheme() string {
+	return a.scheme
+}
+
+func (a stubAuthenticator) Verify(ctx context.Context, credentials string) (*authn.Principal, error) {
+	if credentials != a.credentials {
+		return nil, authn.ErrInvalidCredentials
+	}
+
+	principal := a.principal
+	return &principal, nil
+}
+
+func TestChain(t *testing.T) {
+	chain := authn.NewChain(
+		stubAuthenticator{scheme: "Key", credentials: "first", principal: authn.Principal{ClientID: "first"}},
+	).With(
+		stubAuthenticator{scheme: "Key", credentials: "second", principal: authn.Principal{ClientID: "second"}},
+		stubAuthenticator{scheme: "Other", credentials: "first", principal: authn.Principal{ClientID: "other"}},
+	)
+
+	tests := []struct {
+		authorization string
+		want          string
+		wantErr       error
+	}{
+		{authorization: "Key first", want: "first"},
+		{authorization: "key first", want: "first"},
+		{authorization: "Key second", want: "second"},
+		{authorization: "Other first", want: "other"},
+		{authorization: "Key third", wantErr: authn.ErrInvalidCredentials},
+		{authorization: "Bearer first", wantErr: authn.ErrUnsupportedScheme},
+		{authorization: "Key", wantErr: authn.ErrMalformed},
+		{authorization: "Key ", wantErr: authn.ErrMalformed},
+		{authorization: "", wantErr: authn.ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.authorization, func(t *testing.T) {
+			principal, err := chain.Authenticate(context.Background(), tt.authorization)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && principal.ClientID != tt.want {
+				t.Errorf("ClientID = %q, want %q", principal.ClientID, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	hash := sha256.Sum256([]byte("hashed-key"))
+	chain := authn.NewChain(authn.NewAPIKeyAuthenticator().
+		WithKey("plain-key", authn.Principal{ClientID: "plain"}).
+		WithHashedKey(hex.EncodeToString(hash[:]), authn.Principal{ClientID: "hashed"}))
+
+	tests := []struct {
+		authorization string
+		want          string
+		wantErr       error
+	}{
+		{authorization: "ApiKey plain-key", want: "plain"},
+		{authorization: "apikey hashed-key", want: "hashed"},
+		{authorization: "ApiKey " + hex.EncodeToString(hash[:]), wantErr: authn.ErrInvalidCredentials},
+		{authorization: "ApiKey wrong-key", wantErr: authn.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.authorization, func(t *testing.T) {
+			principal, err := chain.Authenticate(context.Background(), tt.authorization)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && principal.ClientID != tt.want {
+				t.Errorf("ClientID = %q, want %q", principal.ClientID, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid hash", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("WithHashedKey() did not panic")
+			}
+		}()
+
+		authn.NewAPIKeyAuthenticator().WithHashedKey("not-a-hash", authn.Principal{})
+	})
+}
+
+func TestParseBasic(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name        string
+		credentials string
+		wantID      string
+		wantSecret  string
+		wantErr     error
+	}{
+		{name: "plain", credentials: encode("client:secret"), wantID: "client", wantSecret: "secret"},
+		{name: "escaped", credentials: encode("my%3Aclient:s%2Bcret"), wantID: "my:client", wantSecret: "s+cret"},
+		{name: "colon in secret", credentials: encode("client:a:b"), wantID: "client", wantSecret: "a:b"},
+		{name: "empty secret", credentials: encode("client:"), wantID: "client"},
+		{name: "no colon", credentials: encode("client"), wantErr: authn.ErrMalformed},
+		{name: "bad escape", credentials: encode("client:%zz"), wantErr: authn.ErrMalformed},
+		{name: "not base64", credentials: "!!!", wantErr: authn.ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientID, clientSecret, err := authn.ParseBasic(tt.credentials)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if clientID != tt.wantID || clientSecret != tt.wantSecret {
+				t.Errorf("ParseBasic() = %q, %q, want %q, %q", clientID, clientSecret, tt.wantID, tt.wantSecret)
+			}
+		})
+	}
+}
+
+type stubClientValidator map[string]string
+
+func (v stubClientValidator) ValidateClient(ctx context.Context, clientID, clientSecret string) (scope.Scopes, error) {
+	if secret, ok := v[clientID]; !ok || secret != clientSecret {
+		return nil, authn.ErrInvalidCredentials
+	}
+
+	return nil, nil
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	chain := authn.NewChain(authn.NewBasicAuthenticator(stubClientValidator{"client": "secret"}))
+	encode := func(s string) string { return "Basic " + base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantErr       error
+	}{
+		{name: "valid", authorization: encode("client:secret")},
+		{name: "wrong secret", authorization: encode("client:wrong"), wantErr: authn.ErrInvalidCredentials},
+		{name: "unknown client", authorization: encode("other:secret"), wantErr: authn.ErrInvalidCredentials},
+		{name: "malformed", authorization: "Basic !!!", wantErr: authn.ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := chain.Authenticate(context.Background(), tt.authorization)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && (principal.ClientID != "client" || principal.Kind() != authn.KindClient) {
+				t.Errorf("principal = %+v, want the client principal", principal)
+			}
+		})
+	}
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	expired := sharedtest.NewConfigBuilder().WithVariable(func(variable *config.Variable) {
+		variable.Authentication.AccessTokenExpiration = -10
+	}).Build(t)
+	expired.TokenEngine = c.TokenEngine
+
+	userToken := sharedtest.MintAccessToken(t, c, 42, "read:user")
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantUserID    int64
+		wantClientID  string
+		wantKind      authn.Kind
+		wantErr       error
+	}{
+		{name: "user", authorization: userToken, wantUserID: 42, wantKind: authn.KindUser},
+		{name: "client", authorization: sharedtest.MintClientToken(t, c, "client"), wantClientID: "client", wantKind: authn.KindClient},
+		{name: "expired", authorization: sharedtest.MintAccessToken(t, expired, 42), wantErr: authn.ErrExpiredCredentials},
+		{name: "tampered", authorization: userToken[:len(userToken)-4] + "AAAA", wantErr: authn.ErrInvalidCredentials},
+		{name: "garbage", authorization: "Bearer garbage", wantErr: authn.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := c.Authenticator.Authenticate(context.Background(), tt.authorization)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if principal.UserID.Int64() != tt.wantUserID || principal.ClientID != tt.wantClientID {
+				t.Errorf("principal = %d %q, want %d %q", principal.UserID, principal.ClientID, tt.wantUserID, tt.wantClientID)
+			}
+
+			if principal.Kind() != tt.wantKind {
+				t.Errorf("Kind() = %v, want %v", principal.Kind(), tt.wantKind)
+			}
+
+			if principal.TokenID == "" || principal.ExpiresAt.IsZero() || principal.AuthTime.IsZero() {
+				t.Errorf("principal = %+v, want the token metadata", principal)
+			}
+		})
+	}
+}
+
+func TestReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: authn.ErrMalformed, want: "malformed"},
+		{err: authn.ErrUnsupportedScheme, want: "unsupported_type"},
+		{err: authn.ErrExpiredCredentials, want: "expired_token"},
+		{err: authn.ErrInvalidCredentials, want: "invalid_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := authn.Reason(tt.err); got != tt.want {
+				t.Errorf("Reason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for _, kind := range []authn.Kind{authn.KindUser, authn.KindClient, authn.KindAny} {
+		got, err := authn.ParseKind(kind.String())
+		if err != nil || got != kind {
+			t.Errorf("ParseKind(%q) = %v, %v, want %v", kind.String(), got, err, kind)
+		}
+	}
+
+	if _, err := authn.ParseKind("admin"); err == nil || !strings.Contains(err.Error(), "admin") {
+		t.Errorf("ParseKind(admin) err = %v, want an error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package authn
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/todennus/x/scope"
+)
+
+// ClientValidator checks the secret of an OAuth2 client. It is implemented
+// by the service owning the clients.
+type ClientValidator interface {
+	// ValidateClient returns the scopes granted to the client if the secret
+	// is correct, or ErrInvalidCredentials otherwise.
+	ValidateClient(ctx context.Context, clientID, clientSecret string) (scope.Scopes, error)
+}
+
+var _ SchemeAuthenticator = (*BasicAuthenticator)(nil)
+
+// BasicAuthenticator verifies the client id and the client secret sent with
+// HTTP Basic authentication, as described in RFC 6749 section 2.3.1.
+type BasicAuthenticator struct {
+	validator ClientValidator
+}
+
+func NewBasicAuthenticator(validator ClientValidator) *BasicAuthenticator {
+	return &BasicAuthenticator{validator: validator}
+}
+
+func (a *BasicAuthenticator) Scheme() string {
+	return "Basic"
+}
+
+func (a *BasicAuthenticator) Verify(ctx context.Context, credentials string) (*Principal, error) {
+	clientID, clientSecret, err := ParseBasic(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := a.validator.ValidateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{ClientID: clientID, Scopes: scopes}, nil
+}
+
+// ParseBasic decodes the credentials of HTTP Basic authentication. The client
+// id and the client secret are form-urlencoded before being joined.
+func ParseBasic(credentials string) (clientID, clientSecret string, err error) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return "", "", ErrMalformed
+	}
+
+	clientID, clientSecret, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return "", "", ErrMalformed
+	}
+
+	if clientID, err = url.QueryUnescape(clientID); err != nil {
+		return "", "", ErrMalformed
+	}
+
+	if clientSecret, err = url.QueryUnescape(clientSecret); err != nil {
+		return "", "", ErrMalformed
+	}
+
+	return clientID, clientSecret, nil
+}
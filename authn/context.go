@@ -0,0 +1,65 @@
+package authn
+
+import (
+	"context"
+
+	"github.com/todennus/x/xcontext"
+)
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	authorizationKey
+)
+
+// WithPrincipal stores the principal in the context. The user id and the
+// scopes are also available via xcontext.RequestUserID and xcontext.Scope.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	ctx = xcontext.WithRequestUserID(ctx, principal.UserID)
+	ctx = xcontext.WithScope(ctx, principal.Scopes)
+	ctx = context.WithValue(ctx, principalKey, principal)
+	return ctx
+}
+
+// RequestPrincipal returns the principal of the authenticated request, or nil
+// if the request is not authenticated. A context which only has a user id,
+// set by xcontext.WithRequestUserID before principals were introduced, has a
+// user principal with the scopes of xcontext.Scope.
+func RequestPrincipal(ctx context.Context) *Principal {
+	if val := ctx.Value(principalKey); val != nil {
+		return val.(*Principal)
+	}
+
+	if userID := xcontext.RequestUserID(ctx); userID != 0 {
+		return &Principal{UserID: userID, Scopes: xcontext.Scope(ctx)}
+	}
+
+	return nil
+}
+
+// RequestClientID returns the OAuth2 client of the authenticated request, or
+// an empty string if it is unknown.
+func RequestClientID(ctx context.Context) string {
+	if principal := RequestPrincipal(ctx); principal != nil {
+		return principal.ClientID
+	}
+
+	return ""
+}
+
+// WithAuthorization stores the authorization which the principal is
+// authenticated with, so that it can be forwarded to other services.
+func WithAuthorization(ctx context.Context, authorization string) context.Context {
+	return context.WithValue(ctx, authorizationKey, authorization)
+}
+
+// RequestAuthorization returns the authorization of the authenticated
+// request, e.g. "Bearer <token>", or an empty string.
+func RequestAuthorization(ctx context.Context) string {
+	if val := ctx.Value(authorizationKey); val != nil {
+		return val.(string)
+	}
+
+	return ""
+}
@@ -0,0 +1,45 @@
+package authn_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/x/xcontext"
+)
+
+func TestWithPrincipal(t *testing.T) {
+	ctx := context.Background()
+	if authn.RequestPrincipal(ctx) != nil || authn.RequestClientID(ctx) != "" || authn.RequestAuthorization(ctx) != "" {
+		t.Fatalf("an empty context has a principal")
+	}
+
+	principal := &authn.Principal{UserID: 42, ClientID: "client", TokenID: "jti"}
+	ctx = authn.WithPrincipal(ctx, principal)
+	ctx = authn.WithAuthorization(ctx, "Bearer token")
+
+	if got := authn.RequestPrincipal(ctx); got != principal {
+		t.Errorf("RequestPrincipal() = %+v, want %+v", got, principal)
+	}
+
+	if got := authn.RequestClientID(ctx); got != "client" {
+		t.Errorf("RequestClientID() = %q, want client", got)
+	}
+
+	if got := authn.RequestAuthorization(ctx); got != "Bearer token" {
+		t.Errorf("RequestAuthorization() = %q, want %q", got, "Bearer token")
+	}
+
+	if got := xcontext.RequestUserID(ctx); got != 42 {
+		t.Errorf("RequestUserID() = %d, want 42", got)
+	}
+}
+
+func TestRequestPrincipalFromUserID(t *testing.T) {
+	ctx := xcontext.WithRequestUserID(context.Background(), 42)
+
+	principal := authn.RequestPrincipal(ctx)
+	if principal == nil || principal.UserID != 42 || principal.Kind() != authn.KindUser {
+		t.Errorf("RequestPrincipal() = %+v, want the user principal 42", principal)
+	}
+}
@@ -0,0 +1,71 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/todennus/shared/scopedef"
+	"github.com/todennus/shared/tokendef"
+	"github.com/todennus/x/token"
+	"github.com/todennus/x/xcontext"
+)
+
+var _ SchemeAuthenticator = (*TokenAuthenticator)(nil)
+
+// TokenAuthenticator verifies OAuth2 access tokens issued by the token engine.
+type TokenAuthenticator struct {
+	engine token.Engine
+}
+
+func NewTokenAuthenticator(engine token.Engine) *TokenAuthenticator {
+	return &TokenAuthenticator{engine: engine}
+}
+
+func (a *TokenAuthenticator) Scheme() string {
+	return a.engine.Type()
+}
+
+func (a *TokenAuthenticator) Verify(ctx context.Context, credentials string) (*Principal, error) {
+	accessToken := tokendef.OAuth2AccessToken{}
+	ok, err := a.engine.Validate(ctx, credentials, &accessToken)
+
+	// The claims error is only kept by jwt if the signature is valid.
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && errors.Is(validationErr.Inner, token.ErrTokenExpired) {
+		return nil, ErrExpiredCredentials
+	}
+
+	if err != nil {
+		xcontext.Logger(ctx).Debug("failed-to-parse-token", "err", err)
+		return nil, ErrInvalidCredentials
+	}
+
+	if !ok {
+		return nil, ErrExpiredCredentials
+	}
+
+	principal := &Principal{
+		ClientID: accessToken.Client(),
+		Scopes:   scopedef.Engine.ParseScopes(accessToken.Scope),
+		TokenID:  accessToken.ID,
+	}
+
+	if clientID, isClient := accessToken.ClientSub(); isClient {
+		principal.ClientID = clientID
+	} else if principal.UserID, err = accessToken.ParseSnowflakeSub(); err != nil {
+		xcontext.Logger(ctx).Debug("invalid-token-subject", "sub", accessToken.Subject)
+		return nil, ErrInvalidCredentials
+	}
+
+	if accessToken.ExpiresAt != 0 {
+		principal.ExpiresAt = time.Unix(int64(accessToken.ExpiresAt), 0)
+	}
+
+	if accessToken.AuthTime != 0 {
+		principal.AuthTime = time.Unix(int64(accessToken.AuthTime), 0)
+	}
+
+	return principal, nil
+}
@@ -0,0 +1,176 @@
+package concurrency
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/todennus/shared/config"
+)
+
+// Priority decides which requests are rejected first when the limiter is
+// full. Normal requests cannot use the reserved part of the limit.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// Limiter limits the number of requests served at the same time. With an
+// adaptive limit, the limit shrinks when the latency grows above the latency
+// observed without load, and grows again when it recovers.
+type Limiter struct {
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+
+	reserve    float64
+	retryAfter time.Duration
+	adaptive   *gradient
+}
+
+// NewFixedLimiter creates a limiter which always allows limit requests.
+func NewFixedLimiter(limit int) *Limiter {
+	return &Limiter{limit: float64(max(limit, 1)), retryAfter: time.Second}
+}
+
+// NewAdaptiveLimiter creates a limiter starting at limit, which is adjusted
+// between minLimit and maxLimit.
+func NewAdaptiveLimiter(limit, minLimit, maxLimit int) *Limiter {
+	minLimit = max(minLimit, 1)
+	maxLimit = max(maxLimit, minLimit)
+
+	l := NewFixedLimiter(min(max(limit, minLimit), maxLimit))
+	l.adaptive = &gradient{min: float64(minLimit), max: float64(maxLimit)}
+	return l
+}
+
+// NewLimiterFromConfig creates a fixed or adaptive limiter depending on the
+// mode of the configuration, which is checked by
+// config.ConcurrencyVariable.Validate. Unknown modes fall back to adaptive.
+func NewLimiterFromConfig(variable config.ConcurrencyVariable) *Limiter {
+	var l *Limiter
+	if variable.Mode == config.ConcurrencyModeFixed {
+		l = NewFixedLimiter(variable.Limit)
+	} else {
+		l = NewAdaptiveLimiter(variable.Limit, variable.MinLimit, variable.MaxLimit)
+	}
+
+	return l.
+		WithReserve(float64(variable.Reserve) / 100).
+		WithRetryAfter(time.Duration(variable.RetryAfter) * time.Second)
+}
+
+// WithReserve keeps a fraction of the limit, between 0 and 1, for high
+// priority requests.
+func (l *Limiter) WithReserve(fraction float64) *Limiter {
+	l.reserve = min(max(fraction, 0), 1)
+	return l
+}
+
+// WithRetryAfter sets the delay suggested to the rejected clients.
+func (l *Limiter) WithRetryAfter(retryAfter time.Duration) *Limiter {
+	l.retryAfter = retryAfter
+	return l
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests being served.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+// Headers returns the Retry-After header to send with rejected requests.
+func (l *Limiter) Headers() map[string]string {
+	return map[string]string{
+		"Retry-After": strconv.Itoa(int(math.Ceil(l.retryAfter.Seconds()))),
+	}
+}
+
+// Acquire takes a slot for a request. It returns false if the limit of the
+// priority is reached, then the request should be rejected. Otherwise, the
+// returned token must be released when the request completes.
+func (l *Limiter) Acquire(priority Priority) (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= l.capacity(priority) {
+		return nil, false
+	}
+
+	l.inFlight++
+	return &Token{limiter: l, start: time.Now()}, true
+}
+
+// capacity returns the number of requests of a priority which can be served
+// at the same time. Normal requests can always use at least one slot.
+func (l *Limiter) capacity(priority Priority) int {
+	if priority == PriorityHigh {
+		return int(l.limit)
+	}
+
+	return max(int(l.limit*(1-l.reserve)), 1)
+}
+
+func (l *Limiter) release(rtt time.Duration, dropped bool, sample bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if sample && l.adaptive != nil {
+		l.limit = l.adaptive.update(l.limit, l.inFlight, rtt, dropped)
+	}
+
+	l.inFlight--
+}
+
+// Token is a slot taken by a request.
+type Token struct {
+	limiter  *Limiter
+	start    time.Time
+	released bool
+}
+
+// Release returns the slot and reports the latency of the request to an
+// adaptive limiter. Dropped requests, e.g. timed out, shrink the limit.
+func (t *Token) Release(dropped bool) {
+	if t.released {
+		return
+	}
+
+	t.released = true
+	t.limiter.release(time.Since(t.start), dropped, true)
+}
+
+// Discard returns the slot without reporting the latency, e.g. for streams
+// whose duration does not reflect the load of the server.
+func (t *Token) Discard() {
+	if t.released {
+		return
+	}
+
+	t.released = true
+	t.limiter.release(0, false, false)
+}
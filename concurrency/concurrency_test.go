@@ -0,0 +1,102 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	// 2 of the 10 slots are reserved for high priority requests.
+	limiter := NewFixedLimiter(10).WithReserve(0.2)
+
+	var normal []*Token
+	for range 8 {
+		token, ok := limiter.Acquire(PriorityNormal)
+		if !ok {
+			t.Fatalf("normal request %d is rejected, want accepted", len(normal)+1)
+		}
+		normal = append(normal, token)
+	}
+
+	if _, ok := limiter.Acquire(PriorityNormal); ok {
+		t.Fatal("normal request is accepted in the reserved slots, want rejected")
+	}
+
+	for i := range 2 {
+		if _, ok := limiter.Acquire(PriorityHigh); !ok {
+			t.Fatalf("high priority request %d is rejected, want accepted", i+1)
+		}
+	}
+
+	if _, ok := limiter.Acquire(PriorityHigh); ok {
+		t.Fatal("high priority request is accepted over the limit, want rejected")
+	}
+
+	normal[0].Release(false)
+	normal[0].Release(false)
+	if got := limiter.InFlight(); got != 9 {
+		t.Fatalf("in flight = %d, want 9 after releasing a token twice", got)
+	}
+
+	if _, ok := limiter.Acquire(PriorityNormal); ok {
+		t.Fatal("normal request is accepted while high priority requests use the reserve, want rejected")
+	}
+
+	if _, ok := limiter.Acquire(PriorityHigh); !ok {
+		t.Fatal("high priority request is rejected after a release, want accepted")
+	}
+}
+
+func TestLimiterNormalKeepsOneSlot(t *testing.T) {
+	limiter := NewFixedLimiter(1).WithReserve(1)
+
+	if _, ok := limiter.Acquire(PriorityNormal); !ok {
+		t.Fatal("normal request is rejected by a full reserve, want one slot")
+	}
+}
+
+func TestAdaptiveLimiterShrinksOnDrop(t *testing.T) {
+	limiter := NewAdaptiveLimiter(100, 10, 1000)
+
+	for range 50 {
+		token, _ := limiter.Acquire(PriorityNormal)
+		token.Release(true)
+	}
+
+	if got := limiter.Limit(); got != 10 {
+		t.Fatalf("limit = %d, want the min limit 10 after dropped requests", got)
+	}
+
+	token, _ := limiter.Acquire(PriorityNormal)
+	token.Discard()
+	if got := limiter.Limit(); got != 10 {
+		t.Fatalf("limit = %d, want 10 after a discarded token", got)
+	}
+}
+
+func TestGradientFollowsLatency(t *testing.T) {
+	g := &gradient{min: 10, max: 1000}
+	limit := 100.0
+
+	// The server is loaded, but the latency is stable: the limit grows.
+	for range gradientWarmup {
+		limit = g.update(limit, int(limit), 10*time.Millisecond, false)
+	}
+	if limit <= 100 {
+		t.Fatalf("limit = %.1f, want it to grow above 100 with a stable latency", limit)
+	}
+
+	// The latency grows: the limit shrinks.
+	grown := limit
+	for range 20 {
+		limit = g.update(limit, int(limit), 50*time.Millisecond, false)
+	}
+	if limit >= grown {
+		t.Fatalf("limit = %.1f, want it to shrink below %.1f when the latency grows", limit, grown)
+	}
+
+	// The server is not loaded enough to tell: the limit is kept.
+	if got := g.update(limit, 0, time.Second, false); got != limit {
+		t.Fatalf("limit = %.1f, want %.1f with few requests in flight", got, limit)
+	}
+}
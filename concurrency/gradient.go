@@ -0,0 +1,72 @@
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// gradientWarmup is the number of samples averaged before the long-term
+	// latency starts to move slowly.
+	gradientWarmup = 10
+
+	// gradientWindow is the number of samples of the moving average of the
+	// long-term latency.
+	gradientWindow = 600
+
+	// gradientSmoothing is the weight of a new limit against the current one.
+	gradientSmoothing = 0.2
+
+	// gradientBackoff is the factor applied to the limit when a request is
+	// dropped.
+	gradientBackoff = 0.9
+)
+
+// gradient adjusts the limit by the ratio between the long-term latency,
+// which approaches the latency without load, and the latency of the last
+// request. A queue of sqrt(limit) requests is allowed, so that the limit can
+// grow while the latency is stable.
+type gradient struct {
+	min, max float64
+
+	longRTT float64
+	samples int
+}
+
+func (g *gradient) update(limit float64, inFlight int, rtt time.Duration, dropped bool) float64 {
+	if dropped {
+		return g.clamp(limit * gradientBackoff)
+	}
+
+	shortRTT := float64(rtt)
+	if shortRTT <= 0 {
+		return limit
+	}
+
+	if g.samples < gradientWarmup {
+		g.longRTT = (g.longRTT*float64(g.samples) + shortRTT) / float64(g.samples+1)
+		g.samples++
+	} else {
+		g.longRTT += (shortRTT - g.longRTT) * 2 / (gradientWindow + 1)
+	}
+
+	// Let the long-term latency recover faster after an overload, otherwise
+	// the limit keeps growing while the latency is far below it.
+	if g.longRTT/shortRTT > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// The server is not loaded enough to tell whether the limit is too high.
+	if float64(inFlight) < limit/2 {
+		return limit
+	}
+
+	ratio := min(max(g.longRTT/shortRTT, 0.5), 1)
+	newLimit := limit*ratio + math.Sqrt(limit)
+
+	return g.clamp(limit*(1-gradientSmoothing) + newLimit*gradientSmoothing)
+}
+
+func (g *gradient) clamp(limit float64) float64 {
+	return min(max(limit, g.min), g.max)
+}
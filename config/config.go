@@ -1,11 +1,16 @@
 package config
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/redis/go-redis/v9"
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/sessionstore"
 	"github.com/todennus/x/logging"
 	"github.com/todennus/x/session"
 	"github.com/todennus/x/token"
@@ -18,7 +23,9 @@ type Config struct {
 
 	Logger         logging.Logger
 	TokenEngine    token.Engine
+	Authenticator  *authn.Chain
 	SessionManager *session.Manager
+	SessionBackend sessionstore.Backend
 }
 
 func (c *Config) NewSnowflakeNode() *snowflake.Node {
@@ -29,6 +36,15 @@ func (c *Config) NewSnowflakeNode() *snowflake.Node {
 	return result
 }
 
+func (c *Config) NewRedisClient() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     c.Variable.Redis.Addr,
+		DB:       c.Variable.Redis.DB,
+		Username: c.Secret.Redis.Username,
+		Password: c.Secret.Redis.Password,
+	})
+}
+
 func Load(paths ...string) (*Config, error) {
 	if len(paths) > 0 {
 		if err := godotenv.Load(paths...); err != nil {
@@ -36,17 +52,27 @@ func Load(paths ...string) (*Config, error) {
 		}
 	}
 
-	c := &Config{}
-	c.Variable = DefaultVariable()
+	variable := DefaultVariable()
+	if err := load(&variable); err != nil {
+		return nil, err
+	}
 
-	if err := load(&c.Variable); err != nil {
+	secret := Secret{}
+	if err := load(&secret); err != nil {
 		return nil, err
 	}
 
-	if err := load(&c.Secret); err != nil {
+	return New(variable, secret)
+}
+
+// New creates a config from the given variables and secrets instead of the
+// environment, e.g. in tests.
+func New(variable Variable, secret Secret) (*Config, error) {
+	if err := variable.Validate(); err != nil {
 		return nil, err
 	}
 
+	c := &Config{Variable: variable, Secret: secret}
 	if err := c.loadInfras(); err != nil {
 		return nil, err
 	}
@@ -91,7 +117,26 @@ func (c *Config) loadInfras() error {
 	}
 
 	c.TokenEngine = tokenEngine
+
+	// Authenticator, services may append other authenticators to the chain.
+	c.Authenticator = authn.NewChain(authn.NewTokenAuthenticator(tokenEngine))
+
+	// Session
 	c.SessionManager = session.NewManager("/", c.Variable.Session.Expiration)
 
+	// Session backend
+	lifetime := time.Duration(c.Variable.Session.Expiration) * time.Second
+	idleTimeout := time.Duration(c.Variable.Session.IdleTimeout) * time.Second
+	switch c.Variable.Session.Store {
+	case SessionStoreCookie:
+		if c.Secret.Session.AuthenticationKey != "" {
+			c.SessionBackend = sessionstore.NewCookieBackend(lifetime, idleTimeout, c.Secret.Session.KeyPairs()...)
+		}
+	case SessionStoreRedis:
+		c.SessionBackend = sessionstore.NewRedisBackend(c.NewRedisClient(), lifetime, idleTimeout)
+	default:
+		return fmt.Errorf("invalid session store %q", c.Variable.Session.Store)
+	}
+
 	return nil
 }
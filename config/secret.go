@@ -34,4 +34,26 @@ type RedisSecret struct {
 type SessionSecret struct {
 	AuthenticationKey string `envconfig:"authentication_key"`
 	EncryptionKey     string `envconfig:"encryption_key"`
+
+	// The keys before a rotation. Sessions issued with these keys are still
+	// accepted, then re-issued with the current keys. The i-th authentication
+	// key is paired with the i-th encryption key.
+	PreviousAuthenticationKeys []string `envconfig:"previous_authentication_keys"`
+	PreviousEncryptionKeys     []string `envconfig:"previous_encryption_keys"`
+}
+
+// KeyPairs returns the authentication and encryption key pairs, from the
+// current to the oldest one.
+func (s SessionSecret) KeyPairs() [][]byte {
+	pairs := [][]byte{[]byte(s.AuthenticationKey), []byte(s.EncryptionKey)}
+	for i, authenticationKey := range s.PreviousAuthenticationKeys {
+		var encryptionKey []byte
+		if i < len(s.PreviousEncryptionKeys) {
+			encryptionKey = []byte(s.PreviousEncryptionKeys[i])
+		}
+
+		pairs = append(pairs, []byte(authenticationKey), encryptionKey)
+	}
+
+	return pairs
 }
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/todennus/shared/authn"
 	"github.com/todennus/x/logging"
 	gormlogger "gorm.io/gorm/logger"
 )
@@ -12,6 +17,10 @@ type Variable struct {
 	Authentication AuthenticationVariable `envconfig:"authentication"`
 	OAuth2         OAuth2Variable         `envconfig:"oauth2"`
 	Session        SessionVariable        `envconfig:"session"`
+	RateLimit      RateLimitVariable      `envconfig:"ratelimit"`
+	Concurrency    ConcurrencyVariable    `envconfig:"concurrency"`
+	CORS           CORSVariable           `envconfig:"cors"`
+	GRPC           GRPCVariable           `envconfig:"grpc"`
 }
 
 func DefaultVariable() Variable {
@@ -22,15 +31,30 @@ func DefaultVariable() Variable {
 		Authentication: DefaultAuthenticationVariable(),
 		OAuth2:         DefaultOAuth2Variable(),
 		Session:        DefaultSessionVariable(),
+		RateLimit:      DefaultRateLimitVariable(),
+		Concurrency:    DefaultConcurrencyVariable(),
+		CORS:           DefaultCORSVariable(),
+		GRPC:           DefaultGRPCVariable(),
 	}
 }
 
+// Validate checks the variables which cannot be checked by their type.
+func (v Variable) Validate() error {
+	return errors.Join(v.Concurrency.Validate(), v.CORS.Validate(), v.GRPC.Validate())
+}
+
 type ServerVariable struct {
 	Host           string `envconfig:"host"`
 	Port           int    `envconfig:"port"`
 	NodeID         int    `envconfig:"nodeid"`
 	LogLevel       int    `envconfig:"loglevel"`
-	RequestTimeout int    `envconfig:"timeout"` // The timeout of each request (in millisecond).
+	RequestTimeout int    `envconfig:"timeout"`       // The timeout of each request (in millisecond).
+	MaxBodySize    int64  `envconfig:"max_body_size"` // The max size of each request body (in byte).
+
+	// StreamIdleTimeout closes a stream RPC if no message is sent or received
+	// within this duration. Unlike RequestTimeout, a stream can live as long as
+	// it is active.
+	StreamIdleTimeout int `envconfig:"stream_idle_timeout"` // in millisecond
 }
 
 func DefaultServerVariable() ServerVariable {
@@ -39,7 +63,10 @@ func DefaultServerVariable() ServerVariable {
 		Port:           8080,
 		NodeID:         0,
 		LogLevel:       int(logging.LevelDebug),
-		RequestTimeout: 3000, // 3s
+		RequestTimeout: 3000,    // 3s
+		MaxBodySize:    1 << 20, // 1MiB
+
+		StreamIdleTimeout: 60000, // 60s
 	}
 }
 
@@ -129,12 +156,182 @@ func DefaultOAuth2Variable() OAuth2Variable {
 	}
 }
 
+const (
+	SessionStoreCookie = "cookie"
+	SessionStoreRedis  = "redis"
+)
+
 type SessionVariable struct {
-	Expiration int `envconfig:"expiration"`
+	// Store is where the session data is kept. With SessionStoreCookie, the
+	// whole session is encrypted in the cookie. With SessionStoreRedis, the
+	// cookie only holds an opaque id, so that sessions can be revoked.
+	Store string `envconfig:"store"`
+
+	// Expiration is the absolute lifetime of a session.
+	Expiration int `envconfig:"expiration"` // in second
+
+	// IdleTimeout is the time after which a session expires if it is not
+	// used. Every request renews it. Zero disables the idle timeout.
+	IdleTimeout int `envconfig:"idle_timeout"` // in second
 }
 
 func DefaultSessionVariable() SessionVariable {
 	return SessionVariable{
-		Expiration: 24 * 60 * 60, // 24h
+		Store:       SessionStoreCookie,
+		Expiration:  24 * 60 * 60, // 24h
+		IdleTimeout: 30 * 60,      // 30m
+	}
+}
+
+// RateLimitVariable configures the token-bucket policies of each kind of
+// requester. A policy whose Limit is zero is disabled.
+type RateLimitVariable struct {
+	User   RateLimitPolicyVariable `envconfig:"user"`
+	Client RateLimitPolicyVariable `envconfig:"client"`
+	IP     RateLimitPolicyVariable `envconfig:"ip"`
+}
+
+type RateLimitPolicyVariable struct {
+	Limit  int `envconfig:"limit"`  // The number of requests allowed per period.
+	Period int `envconfig:"period"` // in second
+	Burst  int `envconfig:"burst"`  // The bucket capacity, default to Limit.
+}
+
+func DefaultRateLimitVariable() RateLimitVariable {
+	return RateLimitVariable{
+		User:   RateLimitPolicyVariable{Limit: 300, Period: 60, Burst: 50},  // 5 rps
+		Client: RateLimitPolicyVariable{Limit: 600, Period: 60, Burst: 100}, // 10 rps
+		IP:     RateLimitPolicyVariable{Limit: 120, Period: 60, Burst: 20},  // 2 rps
+	}
+}
+
+const (
+	ConcurrencyModeFixed    = "fixed"
+	ConcurrencyModeAdaptive = "adaptive"
+)
+
+// ConcurrencyVariable configures the limit of requests served at the same
+// time. Requests over the limit are rejected instead of waiting until they
+// time out.
+type ConcurrencyVariable struct {
+	// Mode is ConcurrencyModeFixed to always allow Limit requests, or
+	// ConcurrencyModeAdaptive to adjust the limit between MinLimit and
+	// MaxLimit from the latency of requests, starting at Limit.
+	Mode     string `envconfig:"mode"`
+	Limit    int    `envconfig:"limit"`
+	MinLimit int    `envconfig:"min_limit"`
+	MaxLimit int    `envconfig:"max_limit"`
+
+	// Reserve is the part of the limit which only high priority requests,
+	// e.g. health checks, can use.
+	Reserve int `envconfig:"reserve"` // in percent
+
+	// RetryAfter is the delay suggested to the rejected clients.
+	RetryAfter int `envconfig:"retry_after"` // in second
+}
+
+// Validate rejects an unknown mode.
+func (v ConcurrencyVariable) Validate() error {
+	switch v.Mode {
+	case ConcurrencyModeFixed, ConcurrencyModeAdaptive:
+		return nil
+	default:
+		return fmt.Errorf("concurrency: invalid mode %q, expected %q or %q", v.Mode, ConcurrencyModeFixed, ConcurrencyModeAdaptive)
+	}
+}
+
+func DefaultConcurrencyVariable() ConcurrencyVariable {
+	return ConcurrencyVariable{
+		Mode:       ConcurrencyModeAdaptive,
+		Limit:      100,
+		MinLimit:   10,
+		MaxLimit:   1000,
+		Reserve:    10,
+		RetryAfter: 1,
+	}
+}
+
+type CORSVariable struct {
+	// AllowedOrigins is a list of exact origins (https://app.example.com),
+	// wildcard subdomains (https://*.example.com) or "*" for any origin. CORS
+	// is disabled if it is empty.
+	AllowedOrigins   []string `envconfig:"allowed_origins"`
+	AllowedMethods   []string `envconfig:"allowed_methods"`
+	AllowedHeaders   []string `envconfig:"allowed_headers"`
+	ExposedHeaders   []string `envconfig:"exposed_headers"`
+	AllowCredentials bool     `envconfig:"allow_credentials"`
+	MaxAge           int      `envconfig:"max_age"` // in second
+}
+
+// Validate rejects the wildcard origin with credentials, which would let any
+// website make credentialed requests.
+func (v CORSVariable) Validate() error {
+	if !v.AllowCredentials {
+		return nil
+	}
+
+	for _, origin := range v.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			return errors.New("cors: the wildcard origin cannot be used with allow_credentials")
+		}
 	}
+
+	return nil
+}
+
+func DefaultCORSVariable() CORSVariable {
+	return CORSVariable{
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		MaxAge:         10 * 60, // 10m
+	}
+}
+
+// GRPCVariable overrides the behavior of the gRPC interceptors per method.
+// Methods are given by their full name (/package.Service/Method), or by a
+// prefix ending with "*" (/package.Service/*). Maps are written as
+// "method:value,method:value".
+type GRPCVariable struct {
+	// SkipAuthenticate lists the methods which are not authenticated.
+	SkipAuthenticate []string `envconfig:"skip_authenticate"`
+
+	// Require maps methods to the kind of principal which they require, one
+	// of "user", "client" or "any".
+	Require map[string]string `envconfig:"require"`
+
+	// Scopes lists the scopes required by methods, as "method=scope scope".
+	// It is not a map because scopes contain ":".
+	Scopes []string `envconfig:"scopes"`
+
+	// Timeouts maps methods to their timeout, a negative timeout disables it.
+	Timeouts map[string]int `envconfig:"timeouts"` // in millisecond
+
+	// LogLevels maps methods to the level of their access log.
+	LogLevels map[string]int `envconfig:"log_levels"`
+
+	// HighPriority lists the methods which are rejected last when the server
+	// is overloaded.
+	HighPriority []string `envconfig:"high_priority"`
+}
+
+// Validate rejects the unknown principal kinds and the malformed scope
+// policies, which would otherwise be found when the interceptors are built.
+func (v GRPCVariable) Validate() error {
+	for method, kind := range v.Require {
+		if _, err := authn.ParseKind(kind); err != nil {
+			return fmt.Errorf("grpc: invalid require policy of %s: %w", method, err)
+		}
+	}
+
+	for _, item := range v.Scopes {
+		if method, _, found := strings.Cut(item, "="); !found || method == "" {
+			return fmt.Errorf("grpc: invalid scope policy %q, expected method=scopes", item)
+		}
+	}
+
+	return nil
+}
+
+func DefaultGRPCVariable() GRPCVariable {
+	return GRPCVariable{}
 }
@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/todennus/shared/config"
+)
+
+func TestGRPCVariableValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable config.GRPCVariable
+		wantErr  bool
+	}{
+		{name: "empty", variable: config.GRPCVariable{}},
+		{
+			name: "valid",
+			variable: config.GRPCVariable{
+				Require: map[string]string{"/user.Service/*": "user", "/admin.Service/*": "client"},
+				Scopes:  []string{"/user.Service/Get=todennus/read:user"},
+			},
+		},
+		{name: "invalid kind", variable: config.GRPCVariable{Require: map[string]string{"/user.Service/*": "admin"}}, wantErr: true},
+		{name: "scope without method", variable: config.GRPCVariable{Scopes: []string{"=todennus/read:user"}}, wantErr: true},
+		{name: "scope without separator", variable: config.GRPCVariable{Scopes: []string{"/user.Service/Get"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.variable.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConcurrencyVariableValidate(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{mode: config.ConcurrencyModeFixed},
+		{mode: config.ConcurrencyModeAdaptive},
+		{mode: "unlimited", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			variable := config.DefaultConcurrencyVariable()
+			variable.Mode = tt.mode
+
+			if err := variable.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
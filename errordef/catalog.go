@@ -0,0 +1,134 @@
+package errordef
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/todennus/x/xerror"
+	"google.golang.org/grpc/codes"
+)
+
+// Definition declares how an error is exposed to clients. Response handlers
+// use it when the error is not mapped explicitly.
+type Definition struct {
+	Err error
+
+	HTTPStatus int
+	GRPCCode   codes.Code
+
+	// OAuth2Error is the error code of RFC 6749 which the error corresponds
+	// to, e.g. when the error is returned by an OAuth2 endpoint.
+	OAuth2Error string
+
+	// Retryable is true if the same request may succeed later.
+	Retryable bool
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   []Definition
+	codeIndex = map[string]int{}
+)
+
+func init() {
+	Register(
+		Definition{Err: ErrServer, HTTPStatus: http.StatusInternalServerError, GRPCCode: codes.Internal, OAuth2Error: "server_error"},
+		Definition{Err: ErrServerTimeout, HTTPStatus: http.StatusGatewayTimeout, GRPCCode: codes.DeadlineExceeded, OAuth2Error: "temporarily_unavailable", Retryable: true},
+
+		Definition{Err: ErrRequestInvalid, HTTPStatus: http.StatusBadRequest, GRPCCode: codes.InvalidArgument, OAuth2Error: "invalid_request"},
+		Definition{Err: ErrDuplicated, HTTPStatus: http.StatusConflict, GRPCCode: codes.AlreadyExists, OAuth2Error: "invalid_request"},
+		Definition{Err: ErrNotFound, HTTPStatus: http.StatusNotFound, GRPCCode: codes.NotFound, OAuth2Error: "invalid_request"},
+
+		Definition{Err: ErrRequestTooLarge, HTTPStatus: http.StatusRequestEntityTooLarge, GRPCCode: codes.InvalidArgument, OAuth2Error: "invalid_request"},
+		Definition{Err: ErrRequestMediaUnsupported, HTTPStatus: http.StatusUnsupportedMediaType, GRPCCode: codes.InvalidArgument, OAuth2Error: "invalid_request"},
+
+		Definition{Err: ErrRateLimitExceeded, HTTPStatus: http.StatusTooManyRequests, GRPCCode: codes.ResourceExhausted, OAuth2Error: "temporarily_unavailable", Retryable: true},
+		Definition{Err: ErrServerOverloaded, HTTPStatus: http.StatusServiceUnavailable, GRPCCode: codes.Unavailable, OAuth2Error: "temporarily_unavailable", Retryable: true},
+
+		Definition{Err: ErrIdempotencyConflict, HTTPStatus: http.StatusConflict, GRPCCode: codes.Aborted, OAuth2Error: "invalid_request"},
+
+		Definition{Err: ErrCredentialsInvalid, HTTPStatus: http.StatusUnauthorized, GRPCCode: codes.Unauthenticated, OAuth2Error: "invalid_grant"},
+
+		Definition{Err: ErrUnauthenticated, HTTPStatus: http.StatusUnauthorized, GRPCCode: codes.Unauthenticated, OAuth2Error: "invalid_token"},
+		Definition{Err: ErrForbidden, HTTPStatus: http.StatusForbidden, GRPCCode: codes.PermissionDenied, OAuth2Error: "access_denied"},
+
+		Definition{Err: ErrCSRFTokenInvalid, HTTPStatus: http.StatusForbidden, GRPCCode: codes.PermissionDenied, OAuth2Error: "access_denied"},
+
+		Definition{Err: ErrClientInvalid, HTTPStatus: http.StatusUnauthorized, GRPCCode: codes.Unauthenticated, OAuth2Error: "invalid_client"},
+
+		Definition{Err: ErrScopeInvalid, HTTPStatus: http.StatusBadRequest, GRPCCode: codes.InvalidArgument, OAuth2Error: "invalid_scope"},
+
+		Definition{Err: ErrAuthorizationAccessDenied, HTTPStatus: http.StatusForbidden, GRPCCode: codes.PermissionDenied, OAuth2Error: "access_denied"},
+		Definition{Err: ErrTokenInvalidGrant, HTTPStatus: http.StatusBadRequest, GRPCCode: codes.InvalidArgument, OAuth2Error: "invalid_grant"},
+	)
+}
+
+// Register adds definitions to the catalog, e.g. the errors defined by a
+// service. A definition replaces the previous one of the same code.
+func Register(definitions ...Definition) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	for _, definition := range definitions {
+		code := Code(definition.Err)
+		if i, ok := codeIndex[code]; ok {
+			catalog[i] = definition
+		} else {
+			codeIndex[code] = len(catalog)
+			catalog = append(catalog, definition)
+		}
+	}
+}
+
+// Find returns the definition of the first registered error which err is.
+func Find(err error) (Definition, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	for _, definition := range catalog {
+		if errors.Is(err, definition.Err) {
+			return definition, true
+		}
+	}
+
+	return Definition{}, false
+}
+
+// Lookup returns the registered error of a code, e.g. "not_found", or nil if
+// the code is unknown.
+func Lookup(code string) error {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	if i, ok := codeIndex[code]; ok {
+		return catalog[i].Err
+	}
+
+	return nil
+}
+
+// OAuth2Error returns the RFC 6749 error code of err, or "server_error" if
+// err is not registered.
+func OAuth2Error(err error) string {
+	if definition, ok := Find(err); ok && definition.OAuth2Error != "" {
+		return definition.OAuth2Error
+	}
+
+	return "server_error"
+}
+
+// Code returns the code of an error, e.g. "server_error" for ErrServer, or an
+// empty string if err is nil.
+func Code(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var richError xerror.RichError
+	if errors.As(err, &richError) {
+		return Code(richError.Code())
+	}
+
+	return err.Error()
+}
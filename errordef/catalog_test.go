@@ -0,0 +1,126 @@
+package errordef_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/x/xerror"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCatalog(t *testing.T) {
+	sentinels := []error{
+		errordef.ErrServer,
+		errordef.ErrServerTimeout,
+		errordef.ErrRequestInvalid,
+		errordef.ErrDuplicated,
+		errordef.ErrNotFound,
+		errordef.ErrRequestTooLarge,
+		errordef.ErrRequestMediaUnsupported,
+		errordef.ErrRateLimitExceeded,
+		errordef.ErrServerOverloaded,
+		errordef.ErrIdempotencyConflict,
+		errordef.ErrCredentialsInvalid,
+		errordef.ErrUnauthenticated,
+		errordef.ErrForbidden,
+		errordef.ErrCSRFTokenInvalid,
+		errordef.ErrClientInvalid,
+		errordef.ErrScopeInvalid,
+		errordef.ErrAuthorizationAccessDenied,
+		errordef.ErrTokenInvalidGrant,
+	}
+
+	for _, sentinel := range sentinels {
+		code := errordef.Code(sentinel)
+		t.Run(code, func(t *testing.T) {
+			definition, ok := errordef.Find(sentinel)
+			if !ok || definition.Err != sentinel {
+				t.Fatalf("Find() = %v, %v, want the definition of %v", definition.Err, ok, sentinel)
+			}
+
+			if definition.HTTPStatus == 0 || definition.GRPCCode == codes.OK || definition.OAuth2Error == "" {
+				t.Errorf("definition = %+v, want every field to be declared", definition)
+			}
+
+			if got := errordef.Lookup(code); got != sentinel {
+				t.Errorf("Lookup(%q) = %v, want %v", code, got, sentinel)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantOAuth2 string
+	}{
+		{name: "sentinel", err: errordef.ErrNotFound, wantStatus: http.StatusNotFound, wantOAuth2: "invalid_request"},
+		{name: "enriched", err: xerror.Enrich(errordef.ErrForbidden, "no access"), wantStatus: http.StatusForbidden, wantOAuth2: "access_denied"},
+		{name: "wrapped", err: fmt.Errorf("login: %w", errordef.ErrTokenInvalidGrant), wantStatus: http.StatusBadRequest, wantOAuth2: "invalid_grant"},
+		{name: "violations", err: errordef.NewViolationError(errordef.FieldViolation{Field: "name", Description: "is required"}), wantStatus: http.StatusBadRequest, wantOAuth2: "invalid_request"},
+		{name: "domain", err: errordef.UnexpectedDomainWrap(errordef.ErrServer, "failed"), wantStatus: http.StatusInternalServerError, wantOAuth2: "server_error"},
+		{name: "unknown", err: errors.New("unknown"), wantOAuth2: "server_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition, ok := errordef.Find(tt.err)
+			if ok != (tt.wantStatus != 0) || definition.HTTPStatus != tt.wantStatus {
+				t.Errorf("Find() = %d, %v, want %d", definition.HTTPStatus, ok, tt.wantStatus)
+			}
+
+			if got := errordef.OAuth2Error(tt.err); got != tt.wantOAuth2 {
+				t.Errorf("OAuth2Error() = %q, want %q", got, tt.wantOAuth2)
+			}
+		})
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "sentinel", err: errordef.ErrNotFound, want: "not_found"},
+		{name: "rich sentinel", err: errordef.ErrServerTimeout, want: "server_timeout"},
+		{name: "enriched", err: xerror.Enrich(errordef.ErrForbidden, "no access"), want: "forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errordef.Code(tt.err); got != tt.want {
+				t.Errorf("Code() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	errQuota := errors.New("errordef_test_quota_exceeded")
+
+	errordef.Register(errordef.Definition{Err: errQuota, HTTPStatus: http.StatusTooManyRequests, GRPCCode: codes.ResourceExhausted})
+	if definition, ok := errordef.Find(errQuota); !ok || definition.HTTPStatus != http.StatusTooManyRequests {
+		t.Fatalf("Find() = %+v, %v, want the registered definition", definition, ok)
+	}
+
+	// The definition of the same code is replaced.
+	errordef.Register(errordef.Definition{Err: errQuota, HTTPStatus: http.StatusForbidden, GRPCCode: codes.PermissionDenied})
+	if definition, _ := errordef.Find(errQuota); definition.HTTPStatus != http.StatusForbidden {
+		t.Errorf("HTTPStatus = %d, want %d", definition.HTTPStatus, http.StatusForbidden)
+	}
+
+	if got := errordef.Lookup("errordef_test_quota_exceeded"); got != errQuota {
+		t.Errorf("Lookup() = %v, want %v", got, errQuota)
+	}
+
+	if got := errordef.OAuth2Error(errQuota); got != "server_error" {
+		t.Errorf("OAuth2Error() = %q, want server_error without OAuth2 error", got)
+	}
+}
@@ -15,11 +15,21 @@ var (
 	ErrDuplicated     = errors.New("duplicated")
 	ErrNotFound       = errors.New("not_found")
 
+	ErrRequestTooLarge         = errors.New("request_too_large")
+	ErrRequestMediaUnsupported = errors.New("unsupported_media_type")
+
+	ErrRateLimitExceeded = errors.New("rate_limit_exceeded")
+	ErrServerOverloaded  = errors.New("server_overloaded")
+
+	ErrIdempotencyConflict = errors.New("idempotency_conflict")
+
 	ErrCredentialsInvalid = errors.New("invalid_credentials")
 
 	ErrUnauthenticated = errors.New("unauthenticated")
 	ErrForbidden       = errors.New("forbidden")
 
+	ErrCSRFTokenInvalid = errors.New("invalid_csrf_token")
+
 	ErrClientInvalid = errors.New("invalid_client")
 
 	ErrScopeInvalid = errors.New("invalid_scope")
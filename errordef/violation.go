@@ -0,0 +1,41 @@
+package errordef
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/todennus/x/xerror"
+)
+
+// FieldViolation describes why a field of a request is invalid.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ViolationError is an ErrRequestInvalid carrying the violations of each
+// field, so that they can be rendered separately to the client.
+type ViolationError struct {
+	err        xerror.RichError
+	Violations []FieldViolation
+}
+
+func NewViolationError(violations ...FieldViolation) *ViolationError {
+	descriptions := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s", violation.Field, violation.Description))
+	}
+
+	return &ViolationError{
+		err:        xerror.Enrich(ErrRequestInvalid, "%s", strings.Join(descriptions, "; ")),
+		Violations: violations,
+	}
+}
+
+func (e *ViolationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ViolationError) Unwrap() error {
+	return e.err
+}
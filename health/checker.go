@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// NewWithDependencies creates a Health checking the database and the redis
+// server used by the service. A nil db or redisClient is not checked, the
+// clients are shared with the service and are not closed by Health.
+func NewWithDependencies(db *gorm.DB, redisClient redis.UniversalClient) *Health {
+	h := New()
+	if db != nil {
+		h.WithPostgres(db)
+	}
+
+	if redisClient != nil {
+		h.WithRedis(redisClient)
+	}
+
+	return h
+}
+
+// WithPostgres registers a checker pinging the database as "postgres".
+func (h *Health) WithPostgres(db *gorm.DB) *Health {
+	return h.Register("postgres", PostgresChecker(db))
+}
+
+// WithRedis registers a checker pinging the redis server as "redis".
+func (h *Health) WithRedis(client redis.UniversalClient) *Health {
+	return h.Register("redis", RedisChecker(client))
+}
+
+func PostgresChecker(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+func RedisChecker(client redis.UniversalClient) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the name of the gRPC health service, the interceptors of
+// this module do not authenticate its methods.
+var ServiceName = healthpb.Health_ServiceDesc.ServiceName
+
+// WatchInterval is the interval at which Watch runs the checkers.
+var WatchInterval = 5 * time.Second
+
+var _ healthpb.HealthServer = (*grpcServer)(nil)
+
+// grpcServer implements grpc.health.v1. The empty service is the readiness
+// of the whole server, other services are the names of the checkers.
+type grpcServer struct {
+	healthpb.UnimplementedHealthServer
+
+	health *Health
+}
+
+// RegisterGRPC registers the health service on server.
+func (h *Health) RegisterGRPC(server *grpc.Server) {
+	healthpb.RegisterHealthServer(server, &grpcServer{health: h})
+}
+
+// RegisterReflection registers the reflection service on server, e.g. for
+// grpcurl. It exposes the schema of every service, so it should only be
+// enabled in development or on internal servers.
+func RegisterReflection(server *grpc.Server) {
+	reflection.Register(server)
+}
+
+func (s *grpcServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus, err := s.status(ctx, req.GetService())
+	if err != nil {
+		return nil, err
+	}
+
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+func (s *grpcServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		servingStatus, err := s.status(stream.Context(), req.GetService())
+		if err != nil {
+			servingStatus = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+
+		if servingStatus != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			last = servingStatus
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *grpcServer) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	var err error
+	if service == "" {
+		err = s.health.Ready(ctx)
+	} else {
+		err = s.health.Check(ctx, service)
+	}
+
+	switch {
+	case errors.Is(err, ErrUnknownChecker):
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, status.Errorf(codes.NotFound, "unknown service %q", service)
+	case err != nil:
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	default:
+		return healthpb.HealthCheckResponse_SERVING, nil
+	}
+}
@@ -0,0 +1,91 @@
+package health_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/health"
+	"github.com/todennus/shared/sharedtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func newHealthClient(t *testing.T, h *health.Health) healthpb.HealthClient {
+	g := sharedtest.NewGRPC(t, sharedtest.NewConfig(t), func(server *grpc.Server) {
+		h.RegisterGRPC(server)
+	})
+
+	return healthpb.NewHealthClient(g.Conn)
+}
+
+func TestGRPCCheck(t *testing.T) {
+	h := health.New().
+		Register("postgres", failing(nil)).
+		Register("redis", failing(errDown))
+
+	client := newHealthClient(t, h)
+
+	tests := []struct {
+		service string
+		status  healthpb.HealthCheckResponse_ServingStatus
+		code    codes.Code
+	}{
+		{service: "", status: healthpb.HealthCheckResponse_NOT_SERVING},
+		{service: "postgres", status: healthpb.HealthCheckResponse_SERVING},
+		{service: "redis", status: healthpb.HealthCheckResponse_NOT_SERVING},
+		{service: "unknown", code: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			// The health service is called without any authorization.
+			resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("Check() code = %v, want %v", code, tt.code)
+			}
+
+			if err == nil && resp.GetStatus() != tt.status {
+				t.Errorf("Check() = %v, want %v", resp.GetStatus(), tt.status)
+			}
+		})
+	}
+}
+
+func TestGRPCWatch(t *testing.T) {
+	interval := health.WatchInterval
+	health.WatchInterval = 20 * time.Millisecond
+	t.Cleanup(func() { health.WatchInterval = interval })
+
+	var down atomic.Bool
+	h := health.New().Register("redis", health.CheckerFunc(func(ctx context.Context) error {
+		if down.Load() {
+			return errDown
+		}
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := newHealthClient(t, h).Watch(ctx, &healthpb.HealthCheckRequest{Service: "redis"})
+	if err != nil {
+		t.Fatalf("Watch() err = %v", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Recv() = %v, %v, want SERVING", resp.GetStatus(), err)
+	}
+
+	// Only the changes of the status are sent.
+	down.Store(true)
+
+	resp, err = stream.Recv()
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Recv() = %v, %v, want NOT_SERVING", resp.GetStatus(), err)
+	}
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrUnknownChecker = errors.New("unknown checker")
+
+// Checker reports whether a dependency of the service is ready.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Health runs the named checkers registered by the components of a service.
+// The service is ready when every checker succeeds.
+type Health struct {
+	mu       sync.RWMutex
+	checkers map[string]Checker
+	timeout  time.Duration
+}
+
+func New() *Health {
+	return &Health{checkers: make(map[string]Checker), timeout: 2 * time.Second}
+}
+
+// WithTimeout sets the time allowed to each checker, default to 2s.
+func (h *Health) WithTimeout(timeout time.Duration) *Health {
+	h.timeout = timeout
+	return h
+}
+
+// Register adds a named checker, replacing the checker of the same name.
+func (h *Health) Register(name string, checker Checker) *Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checkers[name] = checker
+	return h
+}
+
+// Names returns the names of the registered checkers.
+func (h *Health) Names() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	names := make([]string, 0, len(h.checkers))
+	for name := range h.checkers {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// Check runs a single checker. It returns ErrUnknownChecker if no checker is
+// registered with name.
+func (h *Health) Check(ctx context.Context, name string) error {
+	h.mu.RLock()
+	checker, ok := h.checkers[name]
+	h.mu.RUnlock()
+
+	if !ok {
+		return ErrUnknownChecker
+	}
+
+	return h.run(ctx, checker)
+}
+
+// CheckAll runs every checker concurrently and returns their results by name.
+func (h *Health) CheckAll(ctx context.Context) map[string]error {
+	h.mu.RLock()
+	checkers := make(map[string]Checker, len(h.checkers))
+	for name, checker := range h.checkers {
+		checkers[name] = checker
+	}
+	h.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error, len(checkers))
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := h.run(ctx, checker)
+
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Ready returns the first error of the checkers, or nil if all succeed.
+func (h *Health) Ready(ctx context.Context) error {
+	for name, err := range h.CheckAll(ctx) {
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *Health) run(ctx context.Context, checker Checker) error {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	return checker.Check(ctx)
+}
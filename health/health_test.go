@@ -0,0 +1,100 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/todennus/shared/health"
+)
+
+var errDown = errors.New("connection refused")
+
+func failing(err error) health.Checker {
+	return health.CheckerFunc(func(ctx context.Context) error { return err })
+}
+
+func TestHealthCheck(t *testing.T) {
+	h := health.New().
+		Register("up", failing(nil)).
+		Register("down", failing(errDown))
+
+	tests := []struct {
+		name string
+		want error
+	}{
+		{name: "up", want: nil},
+		{name: "down", want: errDown},
+		{name: "unknown", want: health.ErrUnknownChecker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := h.Check(context.Background(), tt.name); !errors.Is(err, tt.want) {
+				t.Errorf("Check() err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthReady(t *testing.T) {
+	ctx := context.Background()
+
+	if err := health.New().Ready(ctx); err != nil {
+		t.Errorf("Ready() without checker err = %v, want nil", err)
+	}
+
+	h := health.New().Register("up", failing(nil))
+	if err := h.Ready(ctx); err != nil {
+		t.Errorf("Ready() err = %v, want nil", err)
+	}
+
+	h.Register("down", failing(errDown))
+	if err := h.Ready(ctx); !errors.Is(err, errDown) {
+		t.Errorf("Ready() err = %v, want %v", err, errDown)
+	}
+
+	results := h.CheckAll(ctx)
+	if len(results) != 2 || results["up"] != nil || !errors.Is(results["down"], errDown) {
+		t.Errorf("CheckAll() = %v, want the result of every checker", results)
+	}
+}
+
+func TestHealthTimeout(t *testing.T) {
+	blocking := health.CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	h := health.New().WithTimeout(50*time.Millisecond).Register("blocking", blocking)
+
+	start := time.Now()
+	if err := h.Check(context.Background(), "blocking"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Check() err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Check() took %v, want the checker to be canceled after the timeout", elapsed)
+	}
+}
+
+func TestNewWithDependencies(t *testing.T) {
+	if names := health.NewWithDependencies(nil, nil).Names(); len(names) != 0 {
+		t.Errorf("Names() = %v, want no checker without dependencies", names)
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer client.Close()
+
+	h := health.NewWithDependencies(nil, client).WithTimeout(time.Second)
+	if names := h.Names(); !slices.Equal(names, []string{"redis"}) {
+		t.Errorf("Names() = %v, want [redis]", names)
+	}
+
+	if err := h.Check(context.Background(), "redis"); err == nil {
+		t.Errorf("Check() err = nil, want the unreachable redis server to be reported")
+	}
+}
@@ -0,0 +1,61 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/todennus/x/xcontext"
+	"github.com/todennus/x/xhttp"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	CheckOK          = "ok"
+	CheckUnavailable = "unavailable"
+)
+
+type httpResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LivenessHandler serves /healthz. It only reports that the process is able
+// to serve requests, the dependencies are not checked.
+func (h *Health) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		xhttp.WriteResponseJSON(w, http.StatusOK, httpResponse{Status: StatusUp})
+	}
+}
+
+// ReadinessHandler serves /readyz. It responds 503 if any checker fails. The
+// errors of the checkers are logged, the response only tells which checker
+// is unavailable.
+func (h *Health) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := http.StatusOK
+		resp := httpResponse{Status: StatusUp, Checks: map[string]string{}}
+		for name, err := range h.CheckAll(r.Context()) {
+			if err != nil {
+				code = http.StatusServiceUnavailable
+				resp.Status = StatusDown
+				resp.Checks[name] = CheckUnavailable
+				xcontext.Logger(r.Context()).Warn("readiness-check-failed", "checker", name, "err", err)
+			} else {
+				resp.Checks[name] = CheckOK
+			}
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		xhttp.WriteResponseJSON(w, code, resp)
+	}
+}
+
+// Mount registers /healthz and /readyz on a router, e.g. a chi.Router or an
+// http.ServeMux. They must be mounted outside of the authenticated routes.
+func (h *Health) Mount(router interface {
+	Handle(pattern string, handler http.Handler)
+}) {
+	router.Handle("/healthz", h.LivenessHandler())
+	router.Handle("/readyz", h.ReadinessHandler())
+}
@@ -0,0 +1,89 @@
+package health_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/todennus/shared/health"
+)
+
+func serve(t *testing.T, h *health.Health, path string) (int, map[string]any) {
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if strings.Contains(w.Body.String(), errDown.Error()) {
+		t.Errorf("body = %s, want the error details to be hidden", w.Body.String())
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body = %s, err = %v", w.Body.String(), err)
+	}
+
+	return w.Code, body
+}
+
+func TestLivenessHandler(t *testing.T) {
+	h := health.New().Register("down", failing(errDown))
+
+	code, body := serve(t, h, "/healthz")
+	if code != http.StatusOK || body["status"] != health.StatusUp {
+		t.Errorf("/healthz = %d %v, want 200 up, the dependencies are not checked", code, body)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkers map[string]health.Checker
+		code     int
+		status   string
+		checks   map[string]any
+	}{
+		{
+			name:     "ready",
+			checkers: map[string]health.Checker{"postgres": failing(nil)},
+			code:     http.StatusOK,
+			status:   health.StatusUp,
+			checks:   map[string]any{"postgres": health.CheckOK},
+		},
+		{
+			name:     "unavailable",
+			checkers: map[string]health.Checker{"postgres": failing(nil), "redis": failing(errDown)},
+			code:     http.StatusServiceUnavailable,
+			status:   health.StatusDown,
+			checks:   map[string]any{"postgres": health.CheckOK, "redis": health.CheckUnavailable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := health.New()
+			for name, checker := range tt.checkers {
+				h.Register(name, checker)
+			}
+
+			code, body := serve(t, h, "/readyz")
+			if code != tt.code || body["status"] != tt.status {
+				t.Errorf("/readyz = %d %v, want %d %s", code, body["status"], tt.code, tt.status)
+			}
+
+			checks, _ := body["checks"].(map[string]any)
+			if len(checks) != len(tt.checks) {
+				t.Fatalf("checks = %v, want %v", checks, tt.checks)
+			}
+
+			for name, want := range tt.checks {
+				if checks[name] != want {
+					t.Errorf("checks[%s] = %v, want %v", name, checks[name], want)
+				}
+			}
+		})
+	}
+}
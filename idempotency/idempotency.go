@@ -0,0 +1,33 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Record is the state of a request identified by an idempotency key.
+type Record struct {
+	// Fingerprint identifies the request which reserved the key, a key must
+	// not be reused by another request.
+	Fingerprint string `json:"fingerprint"`
+
+	// Completed is false while the first request is being processed.
+	Completed bool        `json:"completed"`
+	Status    int         `json:"status,omitempty"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+}
+
+// Store keeps the records of idempotency keys.
+type Store interface {
+	// Reserve creates a pending record for key if it does not exist. If the
+	// key already exists, its record is returned and reserved is false.
+	Reserve(ctx context.Context, key string, fingerprint string, ttl time.Duration) (record *Record, reserved bool, err error)
+
+	// Complete replaces the pending record of key by the final response.
+	Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error
+
+	// Release removes the record of key, so that the request can be retried.
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,56 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+type memoryEntry struct {
+	record  Record
+	expires time.Time
+}
+
+// MemoryStore keeps records in the process memory. It is intended for tests
+// and single-instance deployments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key string, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := s.records[key]; ok && now.Before(entry.expires) {
+		record := entry.record
+		return &record, false, nil
+	}
+
+	record := Record{Fingerprint: fingerprint}
+	s.records[key] = memoryEntry{record: record, expires: now.Add(ttl)}
+	return &record, true, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = memoryEntry{record: *record, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
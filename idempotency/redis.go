@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Store = (*RedisStore)(nil)
+
+// RedisStore keeps records in Redis, so that a retry is recognized by every
+// instance of a service.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key string, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	record := &Record{Fingerprint: fingerprint}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ok, err := s.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ok {
+		return record, true, nil
+	}
+
+	existing, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// The record expired in the meantime.
+		return s.Reserve(ctx, key, fingerprint, ttl)
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	record = &Record{}
+	if err := json.Unmarshal(existing, record); err != nil {
+		return nil, false, err
+	}
+
+	return record, false, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
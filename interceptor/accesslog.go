@@ -0,0 +1,85 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/config"
+	"github.com/todennus/x/logging"
+	"github.com/todennus/x/xcontext"
+	"github.com/todennus/x/xerror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func logAccess(
+	ctx context.Context,
+	config *config.Config,
+	policy MethodPolicy,
+	method string,
+	err error,
+	rtt time.Duration,
+	extra ...any,
+) {
+	code := status.Code(err)
+	attrs := []any{
+		"function", method,
+		"code", code.String(),
+		"rtt", rtt,
+		"node_id", config.Variable.Server.NodeID,
+	}
+
+	if uid := xcontext.RequestUserID(ctx); uid != 0 {
+		attrs = append(attrs, "uid", uid)
+	}
+
+	if clientID := authn.RequestClientID(ctx); clientID != "" {
+		attrs = append(attrs, "client_id", clientID)
+	}
+
+	if errorCode := errorCode(err); errorCode != "" {
+		attrs = append(attrs, "error", errorCode)
+	}
+
+	attrs = append(attrs, extra...)
+	xcontext.Logger(ctx).Log(policy.logLevel(codeLogLevel(code)), "rpc_access", attrs...)
+}
+
+// errorCode extracts the errordef code from a RichError or from an error
+// returned by ResponseHandler.Finalize, whose message has the form
+// "<code>:<description>".
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var richError xerror.RichError
+	if errors.As(err, &richError) {
+		return richError.Code().Error()
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+
+	code, _, found := strings.Cut(st.Message(), ":")
+	if !found {
+		return ""
+	}
+
+	return code
+}
+
+func codeLogLevel(code codes.Code) logging.Level {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return logging.LevelWarn
+	default:
+		return logging.LevelInfo
+	}
+}
@@ -0,0 +1,219 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xcontext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadataKey is the metadata carrying the request id between
+// services.
+const RequestIDMetadataKey = response.RequestIDMetadataKey
+
+// ClientInterceptor enriches the outgoing rpc of a service with the context
+// of the request being served.
+type ClientInterceptor struct {
+	requestID     bool
+	authorization bool
+	timeout       time.Duration
+	logrtt        bool
+	convertError  bool
+}
+
+func NewClientInterceptor() *ClientInterceptor {
+	return &ClientInterceptor{}
+}
+
+// WithRequestID forwards the request id of the context, the called service
+// keeps it if it uses WithBasicContext.
+func (i *ClientInterceptor) WithRequestID() *ClientInterceptor {
+	i.requestID = true
+	return i
+}
+
+// WithAuthorization forwards the bearer token of the authenticated request,
+// so that the called service sees the same principal. Other credentials, e.g.
+// client secrets or API keys, are never forwarded. It must only be used with
+// trusted services.
+func (i *ClientInterceptor) WithAuthorization() *ClientInterceptor {
+	i.authorization = true
+	return i
+}
+
+// WithTimeout sets the deadline of rpcs whose context has none. The deadline
+// of the context, e.g. set by WithTimeout of the server interceptors, is
+// always propagated to the called service.
+func (i *ClientInterceptor) WithTimeout(timeout time.Duration) *ClientInterceptor {
+	i.timeout = timeout
+	return i
+}
+
+func (i *ClientInterceptor) WithLogRoundTripTime() *ClientInterceptor {
+	i.logrtt = true
+	return i
+}
+
+// WithConvertError converts the statuses returned by the called service back
+// into errordef errors, so that they can be checked with errors.Is.
+func (i *ClientInterceptor) WithConvertError() *ClientInterceptor {
+	i.convertError = true
+	return i
+}
+
+func (i *ClientInterceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, cancel := i.outgoingContext(ctx)
+		defer cancel()
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		i.finish(ctx, method, err, time.Since(start))
+
+		return i.error(err)
+	}
+}
+
+func (i *ClientInterceptor) Stream() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, cancel := i.outgoingContext(ctx)
+
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			i.finish(ctx, method, err, time.Since(start))
+			return nil, i.error(err)
+		}
+
+		s := &clientStream{
+			ClientStream: stream,
+			interceptor:  i,
+			ctx:          ctx,
+			cancel:       cancel,
+			method:       method,
+			start:        start,
+		}
+
+		// The stream is also ended when its context is done, e.g. if the
+		// caller stops reading the stream before the end.
+		context.AfterFunc(stream.Context(), func() {
+			s.end(status.FromContextError(ctx.Err()).Err())
+		})
+
+		return s, nil
+	}
+}
+
+func (i *ClientInterceptor) outgoingContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && i.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, i.timeout)
+	}
+
+	if i.requestID {
+		if requestID, ok := response.RequestIDFromContext(ctx); ok {
+			ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, requestID)
+		}
+	}
+
+	if i.authorization {
+		if authorization := authn.RequestAuthorization(ctx); isBearer(authorization) {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+		}
+	}
+
+	return ctx, cancel
+}
+
+func isBearer(authorization string) bool {
+	scheme, credentials, found := strings.Cut(authorization, " ")
+	return found && credentials != "" && strings.EqualFold(scheme, "Bearer")
+}
+
+func (i *ClientInterceptor) finish(ctx context.Context, method string, err error, rtt time.Duration) {
+	if i.logrtt {
+		xcontext.Logger(ctx).Debug("rpc_client_response", "function", method, "code", status.Code(err).String(), "rtt", rtt)
+	}
+}
+
+func (i *ClientInterceptor) error(err error) error {
+	if !i.convertError {
+		return err
+	}
+
+	return response.FromStatus(err)
+}
+
+type clientStream struct {
+	grpc.ClientStream
+
+	interceptor *ClientInterceptor
+	ctx         context.Context
+	cancel      context.CancelFunc
+	method      string
+	start       time.Time
+	once        sync.Once
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.end(err)
+		return s.interceptor.error(err)
+	}
+
+	return err
+}
+
+func (s *clientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	if err != nil {
+		s.end(err)
+		return s.interceptor.error(err)
+	}
+
+	return nil
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if errors.Is(err, io.EOF) {
+		s.end(nil)
+	} else if err != nil {
+		s.end(err)
+		return s.interceptor.error(err)
+	}
+
+	return err
+}
+
+func (s *clientStream) end(err error) {
+	s.once.Do(func() {
+		s.cancel()
+		s.interceptor.finish(s.ctx, s.method, err, time.Since(s.start))
+	})
+}
@@ -0,0 +1,40 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/todennus/shared/concurrency"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/metrics"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xcontext"
+	"github.com/todennus/x/xerror"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// withConcurrencyLimit takes a slot of limiter, or returns an Unavailable
+// status with the retry-after header if it is full.
+func withConcurrencyLimit(ctx context.Context, limiter *concurrency.Limiter, priority concurrency.Priority) (*concurrency.Token, error) {
+	token, ok := limiter.Acquire(priority)
+	if ok {
+		return token, nil
+	}
+
+	metrics.RequestsShed.WithLabelValues("grpc", priority.String()).Inc()
+
+	headers := limiter.Headers()
+	md := metadata.MD{}
+	for key, value := range headers {
+		md.Set(key, value)
+	}
+
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		xcontext.Logger(ctx).Debug("failed-to-set-concurrency-limit-header", "err", err)
+	}
+
+	_, err := response.NewResponseHandler(ctx, any(nil),
+		xerror.Enrich(errordef.ErrServerOverloaded, "too many concurrent requests, retry after %s second(s)", headers["Retry-After"]),
+	).Finalize(ctx)
+	return nil, err
+}
@@ -0,0 +1,21 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/metrics"
+	"google.golang.org/grpc/status"
+)
+
+func observe(ctx context.Context, method string, err error, rtt time.Duration) {
+	code := status.Code(err).String()
+	metrics.GRPCRequests.WithLabelValues(method, code, errorCode(err)).Inc()
+	metrics.GRPCRequestDuration.WithLabelValues(method, code).Observe(rtt.Seconds())
+
+	if errors.Is(context.Cause(ctx), errordef.ErrServerTimeout) {
+		metrics.Timeouts.WithLabelValues("grpc").Inc()
+	}
+}
@@ -0,0 +1,235 @@
+package interceptor
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/concurrency"
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/health"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/shared/scopedef"
+	"github.com/todennus/x/logging"
+	"github.com/todennus/x/scope"
+	"github.com/todennus/x/xerror"
+)
+
+// MethodPolicy overrides the behavior of an interceptor for some methods. The
+// zero value keeps the behavior given by the builder methods.
+type MethodPolicy struct {
+	// SkipAuthenticate disables or, if false, re-enables WithAuthenticate,
+	// e.g. to authenticate a method matched by a prefix which is skipped.
+	SkipAuthenticate *bool
+
+	// Require rejects requests which have no principal of these kinds. It
+	// needs WithAuthenticate to be enabled.
+	Require authn.Kind
+
+	// Scopes must all be granted to the principal.
+	Scopes scope.Scopes
+
+	// Timeout overrides config.Variable.Server.RequestTimeout, or the idle
+	// timeout of streams. NoTimeout disables it.
+	Timeout time.Duration
+
+	// LogLevel overrides the level of the access log, except for the calls
+	// failing with a server error, which are always logged as warnings.
+	LogLevel *logging.Level
+
+	// HighPriority lets the method use the reserved part of the concurrency
+	// limit, so that it is rejected last when the server is overloaded.
+	HighPriority bool
+}
+
+// NoTimeout disables the timeout of a method in MethodPolicy.Timeout, e.g. for
+// streams which are idle most of the time.
+const NoTimeout time.Duration = -1
+
+// SkipAuthenticate is a helper to set MethodPolicy.SkipAuthenticate.
+func SkipAuthenticate(skip bool) *bool {
+	return &skip
+}
+
+// LogLevel is a helper to set MethodPolicy.LogLevel.
+func LogLevel(level logging.Level) *logging.Level {
+	return &level
+}
+
+// merge overrides the fields of p by the non-zero fields of other.
+func (p MethodPolicy) merge(other MethodPolicy) MethodPolicy {
+	if other.SkipAuthenticate != nil {
+		p.SkipAuthenticate = other.SkipAuthenticate
+	}
+
+	if other.Require != 0 {
+		p.Require = other.Require
+	}
+
+	if len(other.Scopes) > 0 {
+		p.Scopes = other.Scopes
+	}
+
+	if other.Timeout != 0 {
+		p.Timeout = other.Timeout
+	}
+
+	if other.LogLevel != nil {
+		p.LogLevel = other.LogLevel
+	}
+
+	if other.HighPriority {
+		p.HighPriority = true
+	}
+
+	return p
+}
+
+func (p MethodPolicy) timeout(defaultTimeout time.Duration) time.Duration {
+	if p.Timeout != 0 {
+		return p.Timeout
+	}
+
+	return defaultTimeout
+}
+
+func (p MethodPolicy) skipAuthenticate() bool {
+	return p.SkipAuthenticate != nil && *p.SkipAuthenticate
+}
+
+func (p MethodPolicy) priority() concurrency.Priority {
+	if p.HighPriority {
+		return concurrency.PriorityHigh
+	}
+
+	return concurrency.PriorityNormal
+}
+
+func (p MethodPolicy) logLevel(level logging.Level) logging.Level {
+	if p.LogLevel != nil && level < logging.LevelWarn {
+		return *p.LogLevel
+	}
+
+	return level
+}
+
+// check rejects the request if its principal does not satisfy the policy.
+func (p MethodPolicy) check(ctx context.Context) error {
+	if p.Require == 0 && len(p.Scopes) == 0 {
+		return nil
+	}
+
+	var err error
+	principal := authn.RequestPrincipal(ctx)
+	switch {
+	case principal == nil:
+		err = xerror.Enrich(errordef.ErrUnauthenticated, "require authentication to access api")
+	case p.Require != 0 && principal.Kind()&p.Require == 0:
+		err = xerror.Enrich(errordef.ErrForbidden, "%s principal cannot access this api", principal.Kind())
+	default:
+		for _, required := range p.Scopes {
+			if !principal.Scopes.Contains(required) {
+				err = xerror.Enrich(errordef.ErrForbidden, "require scope %s", required)
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		_, err = response.NewResponseHandler(ctx, any(nil), err).Finalize(ctx)
+	}
+
+	return err
+}
+
+// defaultPolicies do not authenticate the health checks, which are called by
+// probes without credentials, lower the level of their access log and reject
+// them last when the server is overloaded. Health/Watch only sends a message
+// when the status changes, so it has no idle timeout.
+func defaultPolicies() methodPolicies {
+	policies := methodPolicies{}
+	policies.set("/"+health.ServiceName+"/*", MethodPolicy{
+		SkipAuthenticate: SkipAuthenticate(true),
+		LogLevel:         LogLevel(logging.LevelDebug),
+		HighPriority:     true,
+	})
+	policies.set("/"+health.ServiceName+"/Watch", MethodPolicy{Timeout: NoTimeout})
+
+	return policies
+}
+
+// methodPolicies holds policies by method pattern. A pattern is a full method
+// name, e.g. /package.Service/Method, or a prefix ending with "*".
+type methodPolicies map[string]MethodPolicy
+
+func (policies methodPolicies) set(pattern string, policy MethodPolicy) {
+	policies[pattern] = policies[pattern].merge(policy)
+}
+
+// match returns the policy of a method. The policies of every matching
+// pattern are merged, the more specific ones overriding the others: exact
+// name over prefixes, longer prefixes over shorter ones.
+func (policies methodPolicies) match(fullMethod string) MethodPolicy {
+	var prefixes []string
+	for pattern := range policies {
+		if prefix, isPrefix := strings.CutSuffix(pattern, "*"); isPrefix && strings.HasPrefix(fullMethod, prefix) {
+			prefixes = append(prefixes, pattern)
+		}
+	}
+
+	slices.SortFunc(prefixes, func(a, b string) int { return len(a) - len(b) })
+
+	var result MethodPolicy
+	for _, pattern := range prefixes {
+		result = result.merge(policies[pattern])
+	}
+
+	if policy, ok := policies[fullMethod]; ok {
+		result = result.merge(policy)
+	}
+
+	return result
+}
+
+// withConfig returns the policies given in code, overridden by the policies
+// of the configuration. The configuration is checked by
+// config.GRPCVariable.Validate, the invalid policies are ignored.
+func (policies methodPolicies) withConfig(variable config.GRPCVariable) methodPolicies {
+	result := methodPolicies{}
+	for pattern, policy := range policies {
+		result.set(pattern, policy)
+	}
+
+	for _, pattern := range variable.SkipAuthenticate {
+		result.set(pattern, MethodPolicy{SkipAuthenticate: SkipAuthenticate(true)})
+	}
+
+	for pattern, name := range variable.Require {
+		if kind, err := authn.ParseKind(name); err == nil {
+			result.set(pattern, MethodPolicy{Require: kind})
+		}
+	}
+
+	for _, item := range variable.Scopes {
+		if pattern, scopes, found := strings.Cut(item, "="); found && pattern != "" {
+			result.set(pattern, MethodPolicy{Scopes: scopedef.Engine.ParseScopes(scopes)})
+		}
+	}
+
+	for pattern, timeout := range variable.Timeouts {
+		result.set(pattern, MethodPolicy{Timeout: time.Duration(timeout) * time.Millisecond})
+	}
+
+	for pattern, level := range variable.LogLevels {
+		result.set(pattern, MethodPolicy{LogLevel: LogLevel(logging.Level(level))})
+	}
+
+	for _, pattern := range variable.HighPriority {
+		result.set(pattern, MethodPolicy{HighPriority: true})
+	}
+
+	return result
+}
@@ -0,0 +1,185 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/concurrency"
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/health"
+	"github.com/todennus/shared/scopedef"
+	"github.com/todennus/x/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMethodPoliciesMatch(t *testing.T) {
+	policies := methodPolicies{}
+	policies.set("/*", MethodPolicy{Timeout: time.Second})
+	policies.set("/user.Service/*", MethodPolicy{SkipAuthenticate: SkipAuthenticate(true), Timeout: 2 * time.Second})
+	policies.set("/user.Service/Login", MethodPolicy{HighPriority: true})
+	policies.set("/user.Service/Delete", MethodPolicy{SkipAuthenticate: SkipAuthenticate(false), Require: authn.KindUser})
+
+	tests := []struct {
+		method           string
+		skipAuthenticate bool
+		timeout          time.Duration
+		priority         concurrency.Priority
+		require          authn.Kind
+	}{
+		{method: "/other.Service/Get", timeout: time.Second, priority: concurrency.PriorityNormal},
+		{method: "/user.Service/Get", skipAuthenticate: true, timeout: 2 * time.Second, priority: concurrency.PriorityNormal},
+		{method: "/user.Service/Login", skipAuthenticate: true, timeout: 2 * time.Second, priority: concurrency.PriorityHigh},
+		{method: "/user.Service/Delete", timeout: 2 * time.Second, priority: concurrency.PriorityNormal, require: authn.KindUser},
+		{method: "/user.ServiceV2/Get", timeout: time.Second, priority: concurrency.PriorityNormal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			policy := policies.match(tt.method)
+			if got := policy.skipAuthenticate(); got != tt.skipAuthenticate {
+				t.Errorf("skipAuthenticate() = %v, want %v", got, tt.skipAuthenticate)
+			}
+
+			if got := policy.timeout(time.Minute); got != tt.timeout {
+				t.Errorf("timeout() = %v, want %v", got, tt.timeout)
+			}
+
+			if got := policy.priority(); got != tt.priority {
+				t.Errorf("priority() = %v, want %v", got, tt.priority)
+			}
+
+			if policy.Require != tt.require {
+				t.Errorf("Require = %v, want %v", policy.Require, tt.require)
+			}
+		})
+	}
+}
+
+func TestMethodPolicyLogLevel(t *testing.T) {
+	policy := MethodPolicy{LogLevel: LogLevel(logging.LevelDebug)}
+
+	if got := policy.logLevel(logging.LevelInfo); got != logging.LevelDebug {
+		t.Errorf("logLevel(info) = %v, want debug", got)
+	}
+
+	if got := policy.logLevel(logging.LevelWarn); got != logging.LevelWarn {
+		t.Errorf("logLevel(warn) = %v, want warn, server errors are always warnings", got)
+	}
+
+	if got := (MethodPolicy{}).logLevel(logging.LevelInfo); got != logging.LevelInfo {
+		t.Errorf("logLevel(info) = %v, want info without override", got)
+	}
+}
+
+func TestMethodPoliciesWithConfig(t *testing.T) {
+	policies := methodPolicies{}
+	policies.set("/user.Service/Get", MethodPolicy{Timeout: time.Second, Require: authn.KindUser})
+
+	result := policies.withConfig(config.GRPCVariable{
+		SkipAuthenticate: []string{"/public.Service/*"},
+		Require:          map[string]string{"/user.Service/Get": "any", "/admin.Service/*": "admin"},
+		Scopes:           []string{"/user.Service/Get=todennus/read:user", "=todennus/read:user", "invalid"},
+		Timeouts:         map[string]int{"/user.Service/Get": 500},
+		LogLevels:        map[string]int{"/public.Service/*": int(logging.LevelDebug)},
+		HighPriority:     []string{"/public.Service/Ping"},
+	})
+
+	get := result.match("/user.Service/Get")
+	if get.Timeout != 500*time.Millisecond || get.Require != authn.KindAny {
+		t.Errorf("policy = %+v, want the config to override the code", get)
+	}
+
+	if len(get.Scopes) != 1 || get.Scopes.String() != "todennus/read:user" {
+		t.Errorf("Scopes = %v, want todennus/read:user", get.Scopes)
+	}
+
+	ping := result.match("/public.Service/Ping")
+	if !ping.skipAuthenticate() || !ping.HighPriority || ping.LogLevel == nil || *ping.LogLevel != logging.LevelDebug {
+		t.Errorf("policy = %+v, want the policies of the config", ping)
+	}
+
+	// The invalid entries are ignored, they are reported by the validation
+	// of the config.
+	if admin := result.match("/admin.Service/Get"); admin.Require != 0 {
+		t.Errorf("Require = %v, want the invalid kind to be ignored", admin.Require)
+	}
+
+	// The policies given in code are not modified.
+	if policies.match("/user.Service/Get").Timeout != time.Second {
+		t.Errorf("withConfig() modified the policies given in code")
+	}
+}
+
+func TestDefaultPolicies(t *testing.T) {
+	policies := defaultPolicies()
+
+	check := policies.match("/" + health.ServiceName + "/Check")
+	if !check.skipAuthenticate() || check.priority() != concurrency.PriorityHigh || check.timeout(time.Minute) != time.Minute {
+		t.Errorf("Check policy = %+v, want skipped authentication, high priority and the default timeout", check)
+	}
+
+	watch := policies.match("/" + health.ServiceName + "/Watch")
+	if !watch.skipAuthenticate() || watch.timeout(time.Minute) != NoTimeout {
+		t.Errorf("Watch policy = %+v, want skipped authentication and no timeout", watch)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	noTimeoutCtx, cancelNoTimeout := withTimeout(ctx, NoTimeout)
+	defer cancelNoTimeout()
+
+	if _, ok := noTimeoutCtx.Deadline(); ok {
+		t.Errorf("context has a deadline, want none with NoTimeout")
+	}
+
+	timeoutCtx, cancelTimeout := withTimeout(ctx, time.Minute)
+	defer cancelTimeout()
+
+	if _, ok := timeoutCtx.Deadline(); !ok {
+		t.Errorf("context has no deadline, want one")
+	}
+
+	cancel()
+	if noTimeoutCtx.Err() == nil {
+		t.Errorf("context without timeout is not canceled with its parent")
+	}
+}
+
+func TestMethodPolicyCheck(t *testing.T) {
+	readUser := scopedef.Engine.ParseScopes("todennus/read:user")
+	user := &authn.Principal{UserID: 42, Scopes: readUser}
+	client := &authn.Principal{ClientID: "client"}
+
+	tests := []struct {
+		name      string
+		policy    MethodPolicy
+		principal *authn.Principal
+		want      codes.Code
+	}{
+		{name: "no policy", policy: MethodPolicy{}, want: codes.OK},
+		{name: "unauthenticated", policy: MethodPolicy{Require: authn.KindAny}, want: codes.Unauthenticated},
+		{name: "user", policy: MethodPolicy{Require: authn.KindUser}, principal: user, want: codes.OK},
+		{name: "client of user method", policy: MethodPolicy{Require: authn.KindUser}, principal: client, want: codes.PermissionDenied},
+		{name: "granted scope", policy: MethodPolicy{Scopes: readUser}, principal: user, want: codes.OK},
+		{name: "missing scope", policy: MethodPolicy{Scopes: readUser}, principal: client, want: codes.PermissionDenied},
+		{name: "scope without principal", policy: MethodPolicy{Scopes: readUser}, want: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = authn.WithPrincipal(ctx, tt.principal)
+			}
+
+			if got := status.Code(tt.policy.check(ctx)); got != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/ratelimit"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xcontext"
+	"github.com/todennus/x/xerror"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func withRateLimit(ctx context.Context, limiter *ratelimit.Limiter) error {
+	identities := map[ratelimit.By]string{}
+	for _, by := range limiter.Policies() {
+		switch by {
+		case ratelimit.ByUser:
+			if uid := xcontext.RequestUserID(ctx); uid != 0 {
+				identities[by] = uid.String()
+			}
+		case ratelimit.ByClient:
+			identities[by] = authn.RequestClientID(ctx)
+		case ratelimit.ByIP:
+			identities[by] = rpcRemoteIP(ctx)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, identities)
+	if err != nil {
+		xcontext.Logger(ctx).Warn("failed-to-check-rate-limit", "err", err)
+		return nil
+	}
+
+	if result.Limit > 0 {
+		md := metadata.MD{}
+		for key, value := range result.Headers() {
+			md.Set(key, value)
+		}
+
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			xcontext.Logger(ctx).Debug("failed-to-set-rate-limit-header", "err", err)
+		}
+	}
+
+	if !result.Allowed {
+		_, err := response.NewResponseHandler(ctx, any(nil),
+			xerror.Enrich(errordef.ErrRateLimitExceeded, "too many requests, retry after %s second(s)", result.Headers()["Retry-After"]),
+		).Map(codes.ResourceExhausted, errordef.ErrRateLimitExceeded).Finalize(ctx)
+		return err
+	}
+
+	return nil
+}
+
+func rpcRemoteIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,256 @@
+package interceptor
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/todennus/shared/concurrency"
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/metrics"
+	"github.com/todennus/shared/ratelimit"
+	"github.com/todennus/shared/tracing"
+	"github.com/todennus/shared/validation"
+	"github.com/todennus/x/xcontext"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+type StreamInterceptor struct {
+	basicContext bool
+	timeout      bool
+	authenticate bool
+	logrtt       bool
+	accesslog    bool
+	metrics      bool
+	limiter      *ratelimit.Limiter
+	concurrency  *concurrency.Limiter
+	validator    validation.Validator
+	policies     methodPolicies
+}
+
+func NewStreamInterceptor() *StreamInterceptor {
+	return &StreamInterceptor{policies: defaultPolicies()}
+}
+
+func (i *StreamInterceptor) WithBasicContext() *StreamInterceptor {
+	i.basicContext = true
+	return i
+}
+
+// WithTimeout closes the stream after it has been idle for
+// config.Variable.Server.StreamIdleTimeout.
+func (i *StreamInterceptor) WithTimeout() *StreamInterceptor {
+	i.timeout = true
+	return i
+}
+
+func (i *StreamInterceptor) WithAuthenticate() *StreamInterceptor {
+	i.authenticate = true
+	return i
+}
+
+func (i *StreamInterceptor) WithLogRoundTripTime() *StreamInterceptor {
+	i.logrtt = true
+	return i
+}
+
+func (i *StreamInterceptor) WithAccessLog() *StreamInterceptor {
+	i.accesslog = true
+	return i
+}
+
+func (i *StreamInterceptor) WithMetrics() *StreamInterceptor {
+	i.metrics = true
+	return i
+}
+
+// WithRateLimit checks the limiter once when the stream is opened.
+func (i *StreamInterceptor) WithRateLimit(limiter *ratelimit.Limiter) *StreamInterceptor {
+	i.limiter = limiter
+	return i
+}
+
+// WithConcurrencyLimit rejects streams with Unavailable when limiter is full.
+// A stream holds its slot until it is closed, but its duration is not used to
+// adjust an adaptive limit.
+func (i *StreamInterceptor) WithConcurrencyLimit(limiter *concurrency.Limiter) *StreamInterceptor {
+	i.concurrency = limiter
+	return i
+}
+
+// WithValidation validates every received message. If validator is nil,
+// validation.Default is used.
+func (i *StreamInterceptor) WithValidation(validator validation.Validator) *StreamInterceptor {
+	if validator == nil {
+		validator = validation.Default
+	}
+
+	i.validator = validator
+	return i
+}
+
+// WithMethodPolicy overrides the behavior of the interceptor for the methods
+// matching pattern, which is a full method name or a prefix ending with "*".
+// The policies of config.Variable.GRPC take precedence over it.
+func (i *StreamInterceptor) WithMethodPolicy(pattern string, policy MethodPolicy) *StreamInterceptor {
+	i.policies.set(pattern, policy)
+	return i
+}
+
+func (i *StreamInterceptor) Interceptor(config *config.Config) grpc.StreamServerInterceptor {
+	policies := i.policies.withConfig(config.Variable.GRPC)
+	defaultIdleTimeout := time.Duration(config.Variable.Server.StreamIdleTimeout) * time.Millisecond
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		policy := policies.match(info.FullMethod)
+
+		var span trace.Span
+		if i.basicContext {
+			ctx, span = tracing.StartGRPCServerSpan(ctx, info.FullMethod)
+			ctx = withBasicContext(ctx, config)
+		}
+
+		xcontext.Logger(ctx).Debug(
+			"rpc_request",
+			"function", info.FullMethod,
+			"client_stream", info.IsClientStream,
+			"server_stream", info.IsServerStream,
+			"node_id", config.Variable.Server.NodeID,
+		)
+
+		if i.metrics {
+			inFlight := metrics.GRPCRequestsInFlight.WithLabelValues(info.FullMethod)
+			inFlight.Inc()
+			defer inFlight.Dec()
+		}
+
+		stream := &serverStream{ServerStream: ss, validator: i.validator}
+		if i.timeout {
+			var cancel context.CancelFunc
+			ctx, cancel = stream.withIdleTimeout(ctx, policy.timeout(defaultIdleTimeout))
+			defer cancel()
+		}
+
+		var err error
+		if i.concurrency != nil {
+			var token *concurrency.Token
+			if token, err = withConcurrencyLimit(ctx, i.concurrency, policy.priority()); token != nil {
+				defer token.Discard()
+			}
+		}
+
+		if err == nil && i.authenticate && !policy.skipAuthenticate() {
+			ctx = withAuthenticate(ctx, config.Authenticator)
+		}
+
+		stream.ctx = ctx
+		start := time.Now()
+
+		if err == nil {
+			err = policy.check(ctx)
+		}
+
+		if err == nil && i.limiter != nil {
+			err = withRateLimit(ctx, i.limiter)
+		}
+
+		if err == nil {
+			err = handler(srv, stream)
+		}
+		rtt := time.Since(start)
+
+		sent, received := stream.sent.Load(), stream.received.Load()
+		if i.logrtt {
+			xcontext.Logger(ctx).Debug("rpc_response", "rtt", rtt, "sent", sent, "received", received)
+		}
+
+		if i.accesslog {
+			logAccess(ctx, config, policy, info.FullMethod, err, rtt, "sent", sent, "received", received)
+		}
+
+		if i.metrics {
+			observe(ctx, info.FullMethod, err, rtt)
+		}
+
+		if span != nil {
+			if code := errorCode(err); code != "" {
+				tracing.SetErrorCode(ctx, code)
+			}
+			tracing.EndGRPCServerSpan(span, err)
+		}
+
+		return err
+	}
+}
+
+// serverStream carries the enriched context to the handler and counts the
+// messages passing through the stream.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx       context.Context
+	validator validation.Validator
+	sent      atomic.Int64
+	received  atomic.Int64
+
+	idleTimer   *time.Timer
+	idleTimeout time.Duration
+}
+
+// withIdleTimeout cancels the context with errordef.ErrServerTimeout if no
+// message passes through the stream within the idle timeout. Unlike unary
+// calls, the context is still canceled when the client goes away.
+func (s *serverStream) withIdleTimeout(ctx context.Context, idleTimeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	s.idleTimeout = idleTimeout
+	if s.idleTimeout <= 0 {
+		return ctx, func() { cancel(context.Canceled) }
+	}
+
+	s.idleTimer = time.AfterFunc(s.idleTimeout, func() { cancel(errordef.ErrServerTimeout) })
+
+	return ctx, func() {
+		s.idleTimer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+		s.resetIdleTimer()
+	}
+
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+
+	s.received.Add(1)
+	s.resetIdleTimer()
+
+	if s.validator != nil {
+		return withValidation(s.ctx, s.validator, m)
+	}
+
+	return nil
+}
+
+func (s *serverStream) resetIdleTimer() {
+	if s.idleTimer != nil {
+		s.idleTimer.Reset(s.idleTimeout)
+	}
+}
@@ -0,0 +1,162 @@
+package interceptor_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/health"
+	"github.com/todennus/shared/sharedtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// streamService has a server stream which waits for its context to end and a
+// bidirectional stream echoing every message, registered without generated
+// code.
+var streamService = grpc.ServiceDesc{
+	ServiceName: "interceptortest.Stream",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Wait",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				<-stream.Context().Done()
+				return status.Error(codes.DeadlineExceeded, "stream is idle")
+			},
+		},
+		{
+			StreamName:    "Echo",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				for {
+					msg := &emptypb.Empty{}
+					if err := stream.RecvMsg(msg); err != nil {
+						if errors.Is(err, io.EOF) {
+							return nil
+						}
+
+						return err
+					}
+
+					if err := stream.SendMsg(msg); err != nil {
+						return err
+					}
+				}
+			},
+		},
+	},
+}
+
+func newStreamServer(t *testing.T) *sharedtest.GRPC {
+	c := sharedtest.NewConfigBuilder().WithVariable(func(variable *config.Variable) {
+		variable.Server.StreamIdleTimeout = 100
+	}).Build(t)
+
+	return sharedtest.NewGRPC(t, c, func(server *grpc.Server) {
+		server.RegisterService(&streamService, struct{}{})
+		health.New().RegisterGRPC(server)
+	})
+}
+
+// recvWithin returns the error of recv, or nil if it does not return within
+// the duration.
+func recvWithin(d time.Duration, recv func() error) error {
+	result := make(chan error, 1)
+	go func() {
+		for {
+			if err := recv(); err != nil {
+				result <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	server := newStreamServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := server.Conn.NewStream(ctx, &streamService.Streams[0], "/interceptortest.Stream/Wait")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.SendMsg(&emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = recvWithin(time.Second, func() error { return stream.RecvMsg(&emptypb.Empty{}) })
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("err = %v, want the idle stream to be closed", err)
+	}
+}
+
+func TestStreamActiveIsKept(t *testing.T) {
+	server := newStreamServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := server.Conn.NewStream(ctx, &streamService.Streams[1], "/interceptortest.Stream/Echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 6 {
+		if err := stream.SendMsg(&emptypb.Empty{}); err != nil {
+			t.Fatalf("SendMsg() err = %v", err)
+		}
+
+		if err := stream.RecvMsg(&emptypb.Empty{}); err != nil {
+			t.Fatalf("RecvMsg() err = %v, want the active stream to be kept", err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestStreamHealthWatchHasNoIdleTimeout(t *testing.T) {
+	server := newStreamServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := healthpb.NewHealthClient(server.Conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() err = %v", err)
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("status = %v, want SERVING", resp.GetStatus())
+	}
+
+	// The status does not change, nothing is sent for longer than the idle
+	// timeout.
+	if err := recvWithin(500*time.Millisecond, func() error { _, err := stream.Recv(); return err }); err != nil {
+		t.Errorf("Recv() err = %v, want the watch to be kept", err)
+	}
+}
@@ -2,14 +2,22 @@ package interceptor
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/concurrency"
 	"github.com/todennus/shared/config"
 	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/metrics"
 	"github.com/todennus/shared/middleware"
-	"github.com/todennus/x/token"
+	"github.com/todennus/shared/ratelimit"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/shared/tracing"
+	"github.com/todennus/shared/validation"
 	"github.com/todennus/x/xcontext"
 	"github.com/todennus/x/xcrypto"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -19,10 +27,16 @@ type UnaryInterceptor struct {
 	timeout      bool
 	authenticate bool
 	logrtt       bool
+	accesslog    bool
+	metrics      bool
+	limiter      *ratelimit.Limiter
+	concurrency  *concurrency.Limiter
+	validator    validation.Validator
+	policies     methodPolicies
 }
 
 func NewUnaryInterceptor() *UnaryInterceptor {
-	return &UnaryInterceptor{}
+	return &UnaryInterceptor{policies: defaultPolicies()}
 }
 
 func (i *UnaryInterceptor) WithBasicContext() *UnaryInterceptor {
@@ -45,10 +59,59 @@ func (i *UnaryInterceptor) WithLogRoundTripTime() *UnaryInterceptor {
 	return i
 }
 
+func (i *UnaryInterceptor) WithAccessLog() *UnaryInterceptor {
+	i.accesslog = true
+	return i
+}
+
+func (i *UnaryInterceptor) WithMetrics() *UnaryInterceptor {
+	i.metrics = true
+	return i
+}
+
+func (i *UnaryInterceptor) WithRateLimit(limiter *ratelimit.Limiter) *UnaryInterceptor {
+	i.limiter = limiter
+	return i
+}
+
+// WithConcurrencyLimit rejects calls with Unavailable when limiter is full.
+// Methods with a high priority MethodPolicy can use the reserved part of the
+// limit.
+func (i *UnaryInterceptor) WithConcurrencyLimit(limiter *concurrency.Limiter) *UnaryInterceptor {
+	i.concurrency = limiter
+	return i
+}
+
+// WithValidation validates requests before calling the handler. If validator
+// is nil, validation.Default is used.
+func (i *UnaryInterceptor) WithValidation(validator validation.Validator) *UnaryInterceptor {
+	if validator == nil {
+		validator = validation.Default
+	}
+
+	i.validator = validator
+	return i
+}
+
+// WithMethodPolicy overrides the behavior of the interceptor for the methods
+// matching pattern, which is a full method name or a prefix ending with "*".
+// The policies of config.Variable.GRPC take precedence over it.
+func (i *UnaryInterceptor) WithMethodPolicy(pattern string, policy MethodPolicy) *UnaryInterceptor {
+	i.policies.set(pattern, policy)
+	return i
+}
+
 func (i *UnaryInterceptor) Interceptor(config *config.Config) grpc.UnaryServerInterceptor {
+	policies := i.policies.withConfig(config.Variable.GRPC)
+	defaultTimeout := time.Duration(config.Variable.Server.RequestTimeout) * time.Millisecond
+
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		policy := policies.match(info.FullMethod)
+
+		var span trace.Span
 		if i.basicContext {
-			ctx = middleware.WithBasicContext(ctx, config)
+			ctx, span = tracing.StartGRPCServerSpan(ctx, info.FullMethod)
+			ctx = withBasicContext(ctx, config)
 		}
 
 		xcontext.Logger(ctx).Debug(
@@ -57,41 +120,125 @@ func (i *UnaryInterceptor) Interceptor(config *config.Config) grpc.UnaryServerIn
 			"node_id", config.Variable.Server.NodeID,
 		)
 
+		if i.metrics {
+			inFlight := metrics.GRPCRequestsInFlight.WithLabelValues(info.FullMethod)
+			inFlight.Inc()
+			defer inFlight.Dec()
+		}
+
 		if i.timeout {
 			var cancel context.CancelFunc
-			ctx, cancel = withTimeout(ctx, config)
+			ctx, cancel = withTimeout(ctx, policy.timeout(defaultTimeout))
 			defer cancel()
 		}
 
-		if i.authenticate {
-			ctx = withAuthenticate(ctx, config.TokenEngine)
+		var err error
+		if i.concurrency != nil {
+			var token *concurrency.Token
+			if token, err = withConcurrencyLimit(ctx, i.concurrency, policy.priority()); token != nil {
+				defer func() { token.Release(errors.Is(context.Cause(ctx), errordef.ErrServerTimeout)) }()
+			}
+		}
+
+		if err == nil && i.authenticate && !policy.skipAuthenticate() {
+			ctx = withAuthenticate(ctx, config.Authenticator)
 		}
 
 		start := time.Now()
-		resp, err := handler(ctx, req)
+
+		var resp any
+		if err == nil {
+			err = policy.check(ctx)
+		}
+
+		if err == nil && i.limiter != nil {
+			err = withRateLimit(ctx, i.limiter)
+		}
+
+		if err == nil && i.validator != nil {
+			err = withValidation(ctx, i.validator, req)
+		}
+
+		if err == nil {
+			resp, err = handler(ctx, req)
+		}
+		rtt := time.Since(start)
 
 		if i.logrtt {
-			xcontext.Logger(ctx).Debug("rpc_response", "rtt", time.Since(start))
+			xcontext.Logger(ctx).Debug("rpc_response", "rtt", rtt)
+		}
+
+		if i.accesslog {
+			logAccess(ctx, config, policy, info.FullMethod, err, rtt)
+		}
+
+		if i.metrics {
+			observe(ctx, info.FullMethod, err, rtt)
+		}
+
+		if span != nil {
+			if code := errorCode(err); code != "" {
+				tracing.SetErrorCode(ctx, code)
+			}
+			tracing.EndGRPCServerSpan(span, err)
 		}
 
 		return resp, err
 	}
 }
 
+// withBasicContext keeps the request id forwarded by a client interceptor of
+// the calling service, so that a request can be followed across services.
+func withBasicContext(ctx context.Context, config *config.Config) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if requestID := md.Get(RequestIDMetadataKey); len(requestID) == 1 && isValidRequestID(requestID[0]) {
+			return middleware.WithBasicContextRequestID(ctx, config, requestID[0])
+		}
+	}
+
+	return middleware.WithBasicContext(ctx, config)
+}
+
+func isValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > 64 {
+		return false
+	}
+
+	for _, c := range requestID {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func withValidation(ctx context.Context, validator validation.Validator, req any) error {
+	if err := validator.Validate(ctx, req); err != nil {
+		_, err = response.NewResponseHandler(ctx, any(nil), err).Finalize(ctx)
+		return err
+	}
+
+	return nil
+}
+
 func withRequestID(ctx context.Context) context.Context {
-	ctx = xcontext.WithRequestID(ctx, xcrypto.RandString(16))
+	ctx = response.WithRequestID(ctx, xcrypto.RandString(16))
 	logger := xcontext.Logger(ctx).With("request_id", xcontext.RequestID(ctx))
 	ctx = xcontext.WithLogger(ctx, logger)
 	return ctx
 }
 
-func withTimeout(ctx context.Context, config *config.Config) (context.Context, context.CancelFunc) {
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout < 0 {
+		return context.WithCancel(ctx)
+	}
+
 	ctx = context.WithoutCancel(ctx)
-	timeout := time.Duration(config.Variable.Server.RequestTimeout) * time.Millisecond
 	return context.WithTimeoutCause(ctx, timeout, errordef.ErrServerTimeout)
 }
 
-func withAuthenticate(ctx context.Context, engine token.Engine) context.Context {
+func withAuthenticate(ctx context.Context, authenticator authn.Authenticator) context.Context {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		xcontext.Logger(ctx).Debug("not-found-metadata")
@@ -104,5 +251,5 @@ func withAuthenticate(ctx context.Context, engine token.Engine) context.Context
 		return ctx
 	}
 
-	return middleware.WithAuthenticate(ctx, authorization[0], engine)
+	return middleware.WithAuthenticateBy(ctx, authorization[0], authenticator)
 }
@@ -0,0 +1,68 @@
+package interceptor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/interceptor"
+	"github.com/todennus/shared/sharedtest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type validatedRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TestUnaryValidation(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	unary := interceptor.NewUnaryInterceptor().WithValidation(nil).Interceptor(c)
+	info := &grpc.UnaryServerInfo{FullMethod: "/interceptortest.Service/Create"}
+
+	tests := []struct {
+		name           string
+		req            *validatedRequest
+		wantCode       codes.Code
+		wantViolations int
+	}{
+		{name: "valid", req: &validatedRequest{Name: "alice"}, wantCode: codes.OK},
+		{name: "invalid", req: &validatedRequest{}, wantCode: codes.InvalidArgument, wantViolations: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			_, err := unary(context.Background(), tt.req, info, func(ctx context.Context, req any) (any, error) {
+				called = true
+				return nil, nil
+			})
+
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", st.Code(), tt.wantCode, err)
+			}
+
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCode == codes.OK)
+			}
+
+			violations := 0
+			for _, detail := range st.Details() {
+				if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+					violations = len(badRequest.GetFieldViolations())
+				}
+			}
+
+			if violations != tt.wantViolations {
+				t.Errorf("violations = %d, want %d", violations, tt.wantViolations)
+			}
+
+			if tt.wantCode != codes.OK {
+				sharedtest.AssertGRPCStatus(t, err, tt.wantCode, errordef.ErrRequestInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/config"
+	"github.com/todennus/x/logging"
+	"github.com/todennus/x/xcontext"
+)
+
+// AccessLog writes a single log line per request after it has been served.
+// The request id is carried by the logger of SetupContext. To include the
+// user id, this middleware must be placed after Authentication.
+func AccessLog(config *config.Config) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			rw := newResponseWriter(w)
+
+			start := time.Now()
+			next.ServeHTTP(rw, r)
+			rtt := time.Since(start)
+
+			attrs := []any{
+				"method", r.Method,
+				"uri", r.RequestURI,
+				"route", routePattern(r),
+				"status", rw.status,
+				"bytes", rw.bytes,
+				"rtt", rtt,
+				"rip", r.RemoteAddr,
+				"node_id", config.Variable.Server.NodeID,
+			}
+
+			if uid := xcontext.RequestUserID(ctx); uid != 0 {
+				attrs = append(attrs, "uid", uid)
+			}
+
+			if clientID := authn.RequestClientID(ctx); clientID != "" {
+				attrs = append(attrs, "client_id", clientID)
+			}
+
+			if rw.errorCode != "" {
+				attrs = append(attrs, "error", rw.errorCode)
+			}
+
+			xcontext.Logger(ctx).Log(statusLogLevel(rw.status), "access", attrs...)
+		})
+	}
+}
+
+// routePattern returns the matched route pattern of the request, e.g.
+// /users/{user_id}, or an empty string if no route was matched.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+
+	return r.Pattern
+}
+
+func statusLogLevel(status int) logging.Level {
+	if status >= http.StatusInternalServerError {
+		return logging.LevelWarn
+	}
+
+	return logging.LevelInfo
+}
@@ -2,69 +2,98 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
-	"strings"
 
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/metrics"
 	"github.com/todennus/shared/response"
-	"github.com/todennus/shared/scopedef"
-	"github.com/todennus/shared/tokendef"
+	"github.com/todennus/shared/tracing"
 	"github.com/todennus/x/token"
 	"github.com/todennus/x/xcontext"
 )
 
+// WithAuthenticate authenticates an access token issued by engine, e.g.
+// "Bearer <token>". Use WithAuthenticateBy to accept other schemes.
 func WithAuthenticate(ctx context.Context, authorization string, engine token.Engine) context.Context {
-	if authorization == "" {
-		return ctx
-	}
-
-	tokenType, token, found := strings.Cut(authorization, " ")
-	if !found {
-		return ctx
-	}
+	return WithAuthenticateBy(ctx, authorization, authn.NewChain(authn.NewTokenAuthenticator(engine)))
+}
 
-	if engine.Type() != tokenType {
+// WithAuthenticateBy stores the principal of the authorization in the context
+// if authenticator accepts it, e.g. config.Authenticator.
+func WithAuthenticateBy(ctx context.Context, authorization string, authenticator authn.Authenticator) context.Context {
+	if authorization == "" {
 		return ctx
 	}
 
-	accessToken := tokendef.OAuth2AccessToken{}
-	ok, err := engine.Validate(ctx, token, &accessToken)
+	principal, err := authenticator.Authenticate(ctx, authorization)
 	if err != nil {
-		xcontext.Logger(ctx).Debug("failed-to-parse-token", "err", err)
+		xcontext.Logger(ctx).Debug("failed-to-authenticate", "err", err)
+		metrics.AuthenticationFailures.WithLabelValues(authn.Reason(err)).Inc()
 		return ctx
 	}
 
-	if !ok {
-		xcontext.Logger(ctx).Debug("expired token")
-		return ctx
+	ctx = authn.WithPrincipal(ctx, principal)
+	ctx = authn.WithAuthorization(ctx, authorization)
+	if principal.Kind() == authn.KindUser {
+		tracing.SetUserID(ctx, principal.UserID)
 	}
 
-	ctx = xcontext.WithRequestUserID(ctx, accessToken.SnowflakeSub())
-	ctx = xcontext.WithScope(ctx, scopedef.Engine.ParseScopes(accessToken.Scope))
+	if principal.ClientID != "" {
+		tracing.SetClientID(ctx, principal.ClientID)
+	}
 
-	xcontext.Logger(ctx).Debug("auth-info", "uid", accessToken.Subject, "scope", accessToken.Scope)
+	xcontext.Logger(ctx).Debug("auth-info", "kind", principal.Kind(), "uid", principal.UserID,
+		"client_id", principal.ClientID, "scope", principal.Scopes)
 
 	return ctx
 }
 
+// Authentication authenticates the access tokens issued by engine. Use
+// AuthenticationBy to accept other schemes.
 func Authentication(engine token.Engine) func(http.Handler) http.Handler {
+	return AuthenticationBy(authn.NewChain(authn.NewTokenAuthenticator(engine)))
+}
+
+// AuthenticationBy authenticates the Authorization header of requests with
+// authenticator, e.g. config.Authenticator. Requests without a valid
+// authorization are still served, without principal.
+func AuthenticationBy(authenticator authn.Authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
 			authorization := r.Header.Get("Authorization")
 
-			next.ServeHTTP(w, r.WithContext(WithAuthenticate(ctx, authorization, engine)))
+			next.ServeHTTP(w, r.WithContext(WithAuthenticateBy(ctx, authorization, authenticator)))
 		})
 	}
 }
 
+// RequireAuthentication only accepts requests made on behalf of a user. The
+// contexts which only have xcontext.RequestUserID are still accepted, see
+// authn.RequestPrincipal.
 func RequireAuthentication(handler http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		if xcontext.RequestUserID(ctx) == 0 {
-			response.Write(ctx, w, http.StatusUnauthorized,
-				response.NewRESTErrorResponseWithMessage(ctx, "unauthenticated", "require authentication to access api"))
-		} else {
-			handler(w, r)
+	return RequirePrincipal(authn.KindUser)(handler)
+}
+
+// RequirePrincipal only accepts requests of the given kinds of principal, e.g.
+// authn.KindClient for internal APIs or authn.KindAny for both users and
+// clients.
+func RequirePrincipal(kind authn.Kind) func(http.HandlerFunc) http.HandlerFunc {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			principal := authn.RequestPrincipal(ctx)
+			switch {
+			case principal == nil:
+				response.Write(ctx, w, http.StatusUnauthorized,
+					response.NewRESTErrorResponseWithMessage(ctx, "unauthenticated", "require authentication to access api"))
+			case principal.Kind()&kind == 0:
+				response.Write(ctx, w, http.StatusForbidden,
+					response.NewRESTErrorResponseWithMessage(ctx, "forbidden", fmt.Sprintf("%s principal cannot access this api", principal.Kind())))
+			default:
+				handler(w, r)
+			}
 		}
 	}
 }
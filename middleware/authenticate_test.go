@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sharedtest"
+	"github.com/todennus/x/xcontext"
+)
+
+func TestRequirePrincipal(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	userToken := sharedtest.MintAccessToken(t, c, 42)
+	clientToken := sharedtest.MintClientToken(t, c, "client")
+
+	tests := []struct {
+		name          string
+		kind          authn.Kind
+		authorization string
+		want          int
+	}{
+		{name: "user", kind: authn.KindUser, authorization: userToken, want: http.StatusOK},
+		{name: "client of user api", kind: authn.KindUser, authorization: clientToken, want: http.StatusForbidden},
+		{name: "client", kind: authn.KindClient, authorization: clientToken, want: http.StatusOK},
+		{name: "user of client api", kind: authn.KindClient, authorization: userToken, want: http.StatusForbidden},
+		{name: "any user", kind: authn.KindAny, authorization: userToken, want: http.StatusOK},
+		{name: "any client", kind: authn.KindAny, authorization: clientToken, want: http.StatusOK},
+		{name: "unauthenticated", kind: authn.KindAny, want: http.StatusUnauthorized},
+		{name: "invalid token", kind: authn.KindAny, authorization: "Bearer invalid", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.SetupContext(c)(middleware.AuthenticationBy(c.Authenticator)(
+				middleware.RequirePrincipal(tt.kind)(func(w http.ResponseWriter, r *http.Request) {}),
+			))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestRequireAuthentication checks that the user id set by services without
+// principal is still accepted.
+func TestRequireAuthentication(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+
+	tests := []struct {
+		name   string
+		userID int64
+		want   int
+	}{
+		{name: "user id", userID: 42, want: http.StatusOK},
+		{name: "no user id", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.SetupContext(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := r.Context()
+				if tt.userID != 0 {
+					ctx = xcontext.WithRequestUserID(ctx, 42)
+				}
+
+				middleware.RequireAuthentication(func(w http.ResponseWriter, r *http.Request) {})(w, r.WithContext(ctx))
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
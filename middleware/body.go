@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xerror"
+)
+
+type bodyRule struct {
+	prefix       string
+	maxBytes     int64
+	contentTypes map[string]bool
+	allowed      string
+}
+
+func (rule *bodyRule) match(path string) bool {
+	if rule.prefix == "/" || path == rule.prefix {
+		return true
+	}
+
+	return strings.HasPrefix(path, strings.TrimSuffix(rule.prefix, "/")+"/")
+}
+
+type BodyLimit struct {
+	rules []*bodyRule
+}
+
+func NewBodyLimit() *BodyLimit {
+	return &BodyLimit{}
+}
+
+// WithRoute overrides the max body size of the requests whose path is prefix
+// or under prefix, and requires their body to have one of contentTypes if it
+// is not empty. A zero maxBytes keeps the max body size of the config, so that
+// a route can only restrict its content types, and a negative one disables the
+// limit. When several routes match, the longest prefix is used.
+func (l *BodyLimit) WithRoute(prefix string, maxBytes int64, contentTypes ...string) *BodyLimit {
+	rule := &bodyRule{
+		prefix:       prefix,
+		maxBytes:     maxBytes,
+		contentTypes: make(map[string]bool),
+		allowed:      strings.Join(contentTypes, ", "),
+	}
+
+	for _, contentType := range contentTypes {
+		rule.contentTypes[strings.ToLower(contentType)] = true
+	}
+
+	l.rules = append(l.rules, rule)
+	return l
+}
+
+// Middleware bounds the request body to the max body size of the matched
+// route, or the max body size of the config otherwise. A zero max body size
+// in the config disables the limit.
+func (l *BodyLimit) Middleware(config *config.Config) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			maxBytes := config.Variable.Server.MaxBodySize
+
+			rule := l.route(r.URL.Path)
+			if rule != nil && rule.maxBytes != 0 {
+				maxBytes = rule.maxBytes
+			}
+
+			hasBody := r.ContentLength != 0 || len(r.TransferEncoding) > 0
+			if hasBody && rule != nil && len(rule.contentTypes) > 0 {
+				mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil || !rule.contentTypes[mediaType] {
+					response.WriteError(ctx, w, http.StatusUnsupportedMediaType,
+						xerror.Enrich(errordef.ErrRequestMediaUnsupported, "content type must be one of %s", rule.allowed))
+					return
+				}
+			}
+
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					response.WriteError(ctx, w, http.StatusRequestEntityTooLarge,
+						xerror.Enrich(errordef.ErrRequestTooLarge, "request body must not exceed %d bytes", maxBytes))
+					return
+				}
+
+				// Bodies without Content-Length are bounded while being read.
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l *BodyLimit) route(path string) *bodyRule {
+	var result *bodyRule
+	for _, rule := range l.rules {
+		if rule.match(path) && (result == nil || len(rule.prefix) > len(result.prefix)) {
+			result = rule
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,84 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sharedtest"
+)
+
+func TestBodyLimit(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	c.Variable.Server.MaxBodySize = 16
+
+	limit := middleware.NewBodyLimit().
+		WithRoute("/upload", 64).
+		WithRoute("/json", 0, "application/json").
+		WithRoute("/stream", -1)
+
+	handler := middleware.SetupContext(c)(limit.Middleware(c)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := io.ReadAll(r.Body); err != nil {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+		}),
+	))
+
+	small, large := strings.Repeat("a", 16), strings.Repeat("a", 32)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		chunked     bool
+		want        int
+		wantErr     error
+	}{
+		{name: "global limit", path: "/", body: small, want: http.StatusOK},
+		{name: "global limit exceeded", path: "/", body: large, want: http.StatusRequestEntityTooLarge, wantErr: errordef.ErrRequestTooLarge},
+		{name: "chunked body exceeded", path: "/", body: large, chunked: true, want: http.StatusRequestEntityTooLarge},
+		{name: "route limit", path: "/upload/file", body: large, want: http.StatusOK},
+		{name: "route limit exceeded", path: "/upload", body: large + large + large, want: http.StatusRequestEntityTooLarge, wantErr: errordef.ErrRequestTooLarge},
+		{name: "other prefix", path: "/uploads", body: large, want: http.StatusRequestEntityTooLarge, wantErr: errordef.ErrRequestTooLarge},
+		{name: "content type", path: "/json", contentType: "application/json; charset=utf-8", body: small, want: http.StatusOK},
+		{name: "content type inherits global limit", path: "/json", contentType: "application/json", body: large, want: http.StatusRequestEntityTooLarge, wantErr: errordef.ErrRequestTooLarge},
+		{name: "unsupported content type", path: "/json", contentType: "text/plain", body: small, want: http.StatusUnsupportedMediaType, wantErr: errordef.ErrRequestMediaUnsupported},
+		{name: "missing content type", path: "/json", body: small, want: http.StatusUnsupportedMediaType, wantErr: errordef.ErrRequestMediaUnsupported},
+		{name: "no body without content type", path: "/json", want: http.StatusOK},
+		{name: "disabled limit", path: "/stream", body: large + large + large, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.chunked {
+				// Hide the length of the body.
+				body = io.MultiReader(body)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tt.wantErr != nil {
+				sharedtest.AssertRESTError(t, w.Result(), tt.want, tt.wantErr)
+			} else if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
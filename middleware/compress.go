@@ -0,0 +1,317 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Encoder creates a writer compressing data into w.
+type Encoder func(w io.Writer) (io.WriteCloser, error)
+
+func gzipEncoder(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, gzip.DefaultCompression)
+}
+
+// deflateEncoder produces the zlib format, which is what "deflate" means in
+// HTTP content coding.
+func deflateEncoder(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, zlib.DefaultCompression)
+}
+
+type Compression struct {
+	minSize      int
+	contentTypes map[string]bool
+	encodings    []string // in the order of preference.
+	encoders     map[string]Encoder
+}
+
+// NewCompression supports gzip and deflate for JSON and text responses of at
+// least 1KiB.
+func NewCompression() *Compression {
+	c := &Compression{
+		minSize:      1024,
+		contentTypes: make(map[string]bool),
+		encoders:     make(map[string]Encoder),
+	}
+
+	return c.
+		WithEncoder("deflate", deflateEncoder).
+		WithEncoder("gzip", gzipEncoder).
+		WithContentTypes(
+			"application/json",
+			"application/javascript",
+			"image/svg+xml",
+			"text/css",
+			"text/html",
+			"text/plain",
+		)
+}
+
+// WithMinSize sets the size under which responses are not compressed.
+func (c *Compression) WithMinSize(size int) *Compression {
+	c.minSize = size
+	return c
+}
+
+// WithContentTypes adds media types to the list of compressible responses.
+func (c *Compression) WithContentTypes(contentTypes ...string) *Compression {
+	for _, contentType := range contentTypes {
+		c.contentTypes[strings.ToLower(contentType)] = true
+	}
+
+	return c
+}
+
+// WithEncoder adds a content coding, e.g. zstd. The last added encoding is
+// preferred when the client accepts several ones with the same quality.
+func (c *Compression) WithEncoder(encoding string, encoder Encoder) *Compression {
+	encoding = strings.ToLower(encoding)
+	if _, ok := c.encoders[encoding]; !ok {
+		c.encodings = append([]string{encoding}, c.encodings...)
+	}
+
+	c.encoders[encoding] = encoder
+	return c
+}
+
+// Middleware compresses the responses according to the Accept-Encoding of the
+// request. Redirects, empty responses and responses which already have a
+// Content-Encoding are never compressed.
+func (c *Compression) Middleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := c.negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				compression:    c,
+				encoding:       encoding,
+				status:         http.StatusOK,
+			}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiate returns the accepted encoding with the highest quality, or an
+// empty string if no supported encoding is accepted.
+func (c *Compression) negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if name, value, found := strings.Cut(strings.TrimSpace(params), "="); found && strings.TrimSpace(name) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range c.encodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+
+	return best
+}
+
+func (c *Compression) compressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return c.contentTypes[mediaType]
+}
+
+// compressWriter buffers the beginning of the response until it knows if the
+// response is worth to be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+
+	compression *Compression
+	encoding    string
+	status      int
+	buffer      []byte
+
+	wroteHeader bool // WriteHeader has been called by the handler.
+	decided     bool // The header has been sent to the underlying writer.
+	encoder     io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	// Informational headers are sent immediately and do not end the header.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = code
+
+	if !bodyAllowsCompression(code) {
+		w.passthrough()
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buffer = append(w.buffer, b...)
+	if len(w.buffer) >= w.compression.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		// A flushed response is streamed, the min size cannot be awaited.
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide starts the compression if the response is compressible, then writes
+// the header and the buffered data.
+func (w *compressWriter) decide() error {
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+
+	if !w.compression.compressible(header) {
+		return w.passthrough()
+	}
+
+	encoder, err := w.compression.encoders[w.encoding](w.ResponseWriter)
+	if err != nil {
+		return w.passthrough()
+	}
+
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+
+	w.decided = true
+	w.encoder = encoder
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buffer := w.buffer
+	w.buffer = nil
+	_, err = encoder.Write(buffer)
+	return err
+}
+
+func (w *compressWriter) passthrough() error {
+	if w.decided {
+		return nil
+	}
+
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	_, err := w.ResponseWriter.Write(buffer)
+	return err
+}
+
+// close sends the buffered response which is smaller than the min size, or
+// terminates the compressed stream.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader {
+			// The handler wrote nothing, let the server send its default
+			// response.
+			return
+		}
+
+		w.passthrough()
+		return
+	}
+
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
+
+func bodyAllowsCompression(code int) bool {
+	switch {
+	case code < http.StatusOK:
+		return false
+	case code >= http.StatusMultipleChoices && code < http.StatusBadRequest:
+		return false
+	case code == http.StatusNoContent:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,190 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/todennus/shared/middleware"
+)
+
+func TestCompressionNegotiation(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: ""},
+		{acceptEncoding: "gzip", want: "gzip"},
+		{acceptEncoding: "deflate", want: "deflate"},
+		{acceptEncoding: "gzip, deflate", want: "gzip"},
+		{acceptEncoding: "gzip;q=0.5, deflate", want: "deflate"},
+		{acceptEncoding: "GZIP", want: "gzip"},
+		{acceptEncoding: "gzip;q=0", want: ""},
+		{acceptEncoding: "*", want: "gzip"},
+		{acceptEncoding: "*, gzip;q=0", want: "deflate"},
+		{acceptEncoding: "br", want: ""},
+		{acceptEncoding: "identity", want: ""},
+	}
+
+	body := strings.Repeat("a", 2048)
+	handler := middleware.NewCompression().Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, body)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+
+			if got := decompress(t, tt.want, w.Body); got != body {
+				t.Errorf("body has %d bytes, want %d", len(got), len(body))
+			}
+
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+		})
+	}
+}
+
+func TestCompressionSkips(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+
+	tests := []struct {
+		name        string
+		method      string
+		status      int
+		contentType string
+		encoding    string
+		body        string
+		want        string
+	}{
+		{name: "json", contentType: "application/json", body: large, want: "gzip"},
+		{name: "detected content type", body: large, want: "gzip"},
+		{name: "small body", contentType: "text/plain", body: "small"},
+		{name: "not compressible", contentType: "image/png", body: large},
+		{name: "already encoded", contentType: "text/plain", encoding: "br", body: large, want: "br"},
+		{name: "redirect", status: http.StatusFound, contentType: "text/html", body: large},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "error", status: http.StatusInternalServerError, contentType: "text/plain", body: large, want: "gzip"},
+		{name: "head", method: http.MethodHead, contentType: "text/plain", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.NewCompression().Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+
+				io.WriteString(w, tt.body)
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			r := httptest.NewRequest(method, "/", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if w.Code != status {
+				t.Errorf("status = %d, want %d", w.Code, status)
+			}
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+
+			encoding := tt.want
+			if encoding == tt.encoding {
+				encoding = ""
+			}
+
+			if got := decompress(t, encoding, w.Body); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestCompressionFlush(t *testing.T) {
+	handler := middleware.NewCompression().Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "first")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "second")
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	r, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// A flushed response is compressed even under the min size.
+	if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+
+	if got := decompress(t, "gzip", resp.Body); got != "firstsecond" {
+		t.Errorf("body = %q, want %q", got, "firstsecond")
+	}
+}
+
+func decompress(t *testing.T, encoding string, r io.Reader) string {
+	t.Helper()
+
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(r)
+	case "deflate":
+		r, err = zlib.NewReader(r)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to create %s reader: %v", encoding, err)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read %s body: %v", encoding, err)
+	}
+
+	return string(b)
+}
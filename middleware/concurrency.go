@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/todennus/shared/concurrency"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/metrics"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xerror"
+)
+
+// ConcurrencyLimit rejects requests with 503 when limiter is full, instead of
+// letting them wait until they time out. The requests for which highPriority
+// returns true can use the reserved part of the limit, e.g.
+// HighPriorityPaths("/oauth2/token"); if highPriority is nil,
+// DefaultHighPriority is used. It must be placed after Timeout to shrink an
+// adaptive limit when requests time out.
+func ConcurrencyLimit(limiter *concurrency.Limiter, highPriority func(*http.Request) bool) func(next http.Handler) http.Handler {
+	if highPriority == nil {
+		highPriority = DefaultHighPriority
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			priority := concurrency.PriorityNormal
+			if highPriority(r) {
+				priority = concurrency.PriorityHigh
+			}
+
+			token, ok := limiter.Acquire(priority)
+			if !ok {
+				metrics.RequestsShed.WithLabelValues("http", priority.String()).Inc()
+
+				headers := limiter.Headers()
+				for key, value := range headers {
+					w.Header().Set(key, value)
+				}
+
+				response.WriteError(ctx, w, http.StatusServiceUnavailable,
+					xerror.Enrich(errordef.ErrServerOverloaded, "too many concurrent requests, retry after %s second(s)", headers["Retry-After"]))
+				return
+			}
+			defer func() { token.Release(errors.Is(context.Cause(ctx), errordef.ErrServerTimeout)) }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DefaultHighPriority gives the high priority to health checks, so that
+// instances are not restarted because of an overload.
+func DefaultHighPriority(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz":
+		return true
+	}
+
+	return false
+}
+
+// HighPriorityPaths gives the high priority to health checks and to the
+// requests of paths, e.g. the token endpoint so that users are not logged out
+// because their refresh token grants are rejected. It runs before the limit is
+// checked, so the priority only depends on the path, the body is not read.
+func HighPriorityPaths(paths ...string) func(*http.Request) bool {
+	highPriority := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		highPriority[path] = true
+	}
+
+	return func(r *http.Request) bool {
+		return highPriority[r.URL.Path] || DefaultHighPriority(r)
+	}
+}
@@ -0,0 +1,109 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/todennus/shared/concurrency"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sharedtest"
+)
+
+func TestHighPriorityPaths(t *testing.T) {
+	highPriority := middleware.HighPriorityPaths("/oauth2/token")
+
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{method: http.MethodGet, path: "/healthz", want: true},
+		{method: http.MethodGet, path: "/readyz", want: true},
+		{method: http.MethodPost, path: "/oauth2/token", want: true},
+		{method: http.MethodPost, path: "/oauth2/token/revoke", want: false},
+		{method: http.MethodPost, path: "/users", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			body := strings.NewReader("grant_type=refresh_token&refresh_token=token")
+			r := httptest.NewRequest(tt.method, tt.path, body)
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			if got := highPriority(r); got != tt.want {
+				t.Errorf("highPriority() = %v, want %v", got, tt.want)
+			}
+
+			if body.Len() != int(body.Size()) {
+				t.Errorf("the body was read to decide the priority")
+			}
+		})
+	}
+
+	// Without paths, a refresh token grant is not high priority.
+	r := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader("grant_type=refresh_token"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if middleware.DefaultHighPriority(r) {
+		t.Errorf("DefaultHighPriority() = true, want false for the token endpoint")
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+
+	// The limit has one slot for normal requests and one reserved slot.
+	limiter := concurrency.NewFixedLimiter(2).WithReserve(0.5)
+	token, ok := limiter.Acquire(concurrency.PriorityNormal)
+	if !ok {
+		t.Fatalf("Acquire() = false, want a slot")
+	}
+	defer token.Discard()
+
+	handler := middleware.SetupContext(c)(middleware.ConcurrencyLimit(limiter, middleware.HighPriorityPaths("/oauth2/token"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		})))
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{name: "normal", path: "/users", want: http.StatusServiceUnavailable},
+		{name: "token endpoint", path: "/oauth2/token", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const body = "grant_type=refresh_token&refresh_token=token"
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body)))
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+
+			if tt.want == http.StatusServiceUnavailable {
+				sharedtest.AssertRESTError(t, w.Result(), tt.want, errordef.ErrServerOverloaded)
+				if w.Header().Get("Retry-After") == "" {
+					t.Error("Retry-After is not set")
+				}
+				return
+			}
+
+			if w.Body.String() != body {
+				t.Errorf("body = %q, want %q", w.Body.String(), body)
+			}
+		})
+	}
+
+	if inFlight := limiter.InFlight(); inFlight != 1 {
+		t.Errorf("InFlight() = %d, want the slots of the requests to be released", inFlight)
+	}
+}
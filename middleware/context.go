@@ -5,14 +5,31 @@ import (
 	"net/http"
 
 	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/shared/tracing"
 	"github.com/todennus/x/xcontext"
 	"github.com/todennus/x/xcrypto"
 )
 
+type contextKey int
+
+const (
+	csrfTokenKey contextKey = iota
+	cspNonceKey
+	sessionBindingKey
+)
+
 func WithBasicContext(ctx context.Context, config *config.Config) context.Context {
-	ctx = xcontext.WithRequestID(ctx, xcrypto.RandString(16))
+	return WithBasicContextRequestID(ctx, config, xcrypto.RandString(16))
+}
+
+// WithBasicContextRequestID is the same as WithBasicContext, but keeps the
+// request id of the caller, e.g. when a service is called by another one.
+func WithBasicContextRequestID(ctx context.Context, config *config.Config, requestID string) context.Context {
+	ctx = response.WithRequestID(ctx, requestID)
 	ctx = xcontext.WithSessionManager(ctx, config.SessionManager)
 	ctx = xcontext.WithLogger(ctx, config.Logger.With("request_id", xcontext.RequestID(ctx)))
+	ctx = tracing.WithLogger(ctx)
 
 	return ctx
 }
@@ -20,7 +37,12 @@ func WithBasicContext(ctx context.Context, config *config.Config) context.Contex
 func SetupContext(config *config.Config) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			h.ServeHTTP(w, r.WithContext(WithBasicContext(r.Context(), config)))
+			ctx, span := tracing.StartHTTPServerSpan(r)
+			rw := newResponseWriter(w)
+
+			h.ServeHTTP(rw, r.WithContext(WithBasicContext(ctx, config)))
+
+			tracing.EndHTTPServerSpan(span, r.Method, routePattern(r), rw.status)
 		})
 	}
 }
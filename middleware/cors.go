@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/todennus/shared/config"
+)
+
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          map[string]bool
+	wildcardOrigins  []wildcardOrigin
+	methods          map[string]bool
+	anyHeader        bool
+	headers          map[string]bool
+	allowMethods     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// wildcardOrigin matches every subdomain of host, e.g. https://*.example.com
+// matches https://app.example.com but not https://example.com.
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+}
+
+func newCORSPolicy(variable config.CORSVariable) *corsPolicy {
+	policy := &corsPolicy{
+		origins:          make(map[string]bool),
+		methods:          make(map[string]bool),
+		headers:          make(map[string]bool),
+		allowMethods:     strings.Join(variable.AllowedMethods, ", "),
+		exposeHeaders:    strings.Join(variable.ExposedHeaders, ", "),
+		allowCredentials: variable.AllowCredentials,
+	}
+
+	if variable.MaxAge > 0 {
+		policy.maxAge = strconv.Itoa(variable.MaxAge)
+	}
+
+	for _, origin := range variable.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			policy.anyOrigin = true
+		} else if scheme, host, found := strings.Cut(origin, "://*."); found {
+			policy.wildcardOrigins = append(policy.wildcardOrigins, wildcardOrigin{scheme: scheme, suffix: "." + host})
+		} else {
+			policy.origins[origin] = true
+		}
+	}
+
+	for _, method := range variable.AllowedMethods {
+		policy.methods[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+
+	for _, header := range variable.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		if header == "*" {
+			policy.anyHeader = true
+		} else {
+			policy.headers[http.CanonicalHeaderKey(header)] = true
+		}
+	}
+
+	return policy
+}
+
+func (p *corsPolicy) enabled() bool {
+	return p.anyOrigin || len(p.origins) > 0 || len(p.wildcardOrigins) > 0
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, wildcard := range p.wildcardOrigins {
+		if u.Scheme == wildcard.scheme && strings.HasSuffix(u.Host, wildcard.suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *corsPolicy) allowHeaders(requested string) bool {
+	if p.anyHeader || requested == "" {
+		return true
+	}
+
+	for _, header := range strings.Split(requested, ",") {
+		if !p.headers[http.CanonicalHeaderKey(strings.TrimSpace(header))] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setAllowOrigin writes the allowed origin. With the wildcard, the origin is
+// never echoed and credentials are never allowed, otherwise any website could
+// read the responses with the cookies of the user. config.New rejects this
+// combination.
+func (p *corsPolicy) setAllowOrigin(header http.Header, origin string) {
+	if p.anyOrigin {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	if p.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// CORS handles the preflight requests and adds the CORS headers to responses
+// of allowed origins, as configured by config.Variable.CORS.
+func CORS(config *config.Config) func(next http.Handler) http.Handler {
+	policy := newCORSPolicy(config.Variable.CORS)
+
+	return func(next http.Handler) http.Handler {
+		if !policy.enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			origin := r.Header.Get("Origin")
+			isPreflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if isPreflight {
+				header.Add("Vary", "Origin")
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+
+				requestMethod := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+				requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+				if origin != "" && policy.allowOrigin(origin) &&
+					policy.methods[requestMethod] && policy.allowHeaders(requestHeaders) {
+					policy.setAllowOrigin(header, origin)
+					header.Set("Access-Control-Allow-Methods", policy.allowMethods)
+					if requestHeaders != "" {
+						header.Set("Access-Control-Allow-Headers", requestHeaders)
+					}
+					if policy.maxAge != "" {
+						header.Set("Access-Control-Max-Age", policy.maxAge)
+					}
+				}
+
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			header.Add("Vary", "Origin")
+			if origin != "" && policy.allowOrigin(origin) {
+				policy.setAllowOrigin(header, origin)
+				if policy.exposeHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sharedtest"
+)
+
+func corsHandler(t *testing.T, modify func(cors *config.CORSVariable)) http.Handler {
+	t.Helper()
+
+	c := sharedtest.NewConfigBuilder().WithVariable(func(variable *config.Variable) {
+		variable.CORS.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+		variable.CORS.ExposedHeaders = []string{"X-Request-Id"}
+		variable.CORS.AllowCredentials = true
+		if modify != nil {
+			modify(&variable.CORS)
+		}
+	}).Build(t)
+
+	return middleware.CORS(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+}
+
+func TestCORSRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		origin          string
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{name: "exact origin", origin: "https://app.example.com", wantOrigin: "https://app.example.com", wantCredentials: "true"},
+		{name: "wildcard subdomain", origin: "https://a.example.org", wantOrigin: "https://a.example.org", wantCredentials: "true"},
+		{name: "wildcard apex", origin: "https://example.org"},
+		{name: "wildcard scheme", origin: "http://a.example.org"},
+		{name: "unknown origin", origin: "https://evil.com"},
+		{name: "suffix attack", origin: "https://app.example.com.evil.com"},
+		{name: "no origin"},
+	}
+
+	handler := corsHandler(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != http.StatusTeapot {
+				t.Errorf("status = %d, want the handler to be called", w.Code)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("allow origin = %q, want %q", got, tt.wantOrigin)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("allow credentials = %q, want %q", got, tt.wantCredentials)
+			}
+
+			if w.Header().Get("Vary") != "Origin" {
+				t.Errorf("vary = %q, want Origin", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	tests := []struct {
+		name       string
+		origin     string
+		method     string
+		headers    string
+		wantOrigin string
+	}{
+		{name: "allowed", origin: "https://app.example.com", method: "POST", headers: "Content-Type, Authorization", wantOrigin: "https://app.example.com"},
+		{name: "unknown origin", origin: "https://evil.com", method: "POST"},
+		{name: "method not allowed", origin: "https://app.example.com", method: "TRACE"},
+		{name: "header not allowed", origin: "https://app.example.com", method: "POST", headers: "X-Custom"},
+	}
+
+	handler := corsHandler(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want 204 without calling the handler", w.Code)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("allow origin = %q, want %q", got, tt.wantOrigin)
+			}
+
+			if tt.wantOrigin != "" {
+				if got := w.Header().Get("Access-Control-Allow-Headers"); got != tt.headers {
+					t.Errorf("allow headers = %q, want %q", got, tt.headers)
+				}
+
+				if w.Header().Get("Access-Control-Max-Age") == "" {
+					t.Error("max age is not set")
+				}
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	handler := corsHandler(t, func(cors *config.CORSVariable) {
+		cors.AllowedOrigins = []string{"*"}
+		cors.AllowCredentials = false
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://any.com")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("allow origin = %q, want * instead of the echoed origin", got)
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	handler := corsHandler(t, func(cors *config.CORSVariable) {
+		cors.AllowedOrigins = nil
+	})
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want the handler to be called", w.Code)
+	}
+}
+
+func TestCORSValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    config.CORSVariable
+		wantErr bool
+	}{
+		{name: "wildcard without credentials", cors: config.CORSVariable{AllowedOrigins: []string{"*"}}},
+		{name: "origins with credentials", cors: config.CORSVariable{AllowedOrigins: []string{"https://a.com"}, AllowCredentials: true}},
+		{name: "wildcard with credentials", cors: config.CORSVariable{AllowedOrigins: []string{" * "}, AllowCredentials: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cors.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"mime"
+	"net/http"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xerror"
+	"github.com/todennus/x/xhttp"
+)
+
+// Replace these variables to change the name of the CSRF cookie, header and
+// form field.
+var (
+	CSRFCookieName = "csrf-token"
+	CSRFHeaderName = "X-CSRF-Token"
+	CSRFFieldName  = "csrf_token"
+)
+
+const csrfNonceLength = 16
+
+// CSRFToken returns the token which must be sent back by the next unsafe
+// request, e.g. as a hidden form field in templates.
+func CSRFToken(ctx context.Context) string {
+	if val := ctx.Value(csrfTokenKey); val != nil {
+		return val.(string)
+	}
+
+	return ""
+}
+
+type CSRF struct {
+	doubleSubmit bool
+}
+
+func NewCSRF() *CSRF {
+	return &CSRF{}
+}
+
+// WithDoubleSubmit always binds tokens to a random cookie instead of the
+// session. It is intended for stateless pages which have no session. Without
+// this option, the cookie is only used until a session is available from the
+// session backend of the config.
+func (c *CSRF) WithDoubleSubmit() *CSRF {
+	c.doubleSubmit = true
+	return c
+}
+
+// Middleware issues a CSRF token for every request and validates the token
+// of unsafe requests, either from the CSRF header or the CSRF form field. It
+// must be placed after WithSessionStore.
+func (c *CSRF) Middleware(config *config.Config) func(next http.Handler) http.Handler {
+	if config.Secret.Session.AuthenticationKey == "" {
+		config.Logger.Critical("csrf-key-not-configured",
+			"err", "the session authentication key is empty, csrf tokens are only accepted by this instance until it restarts")
+	}
+
+	key := csrfKey(config.Secret.Session.AuthenticationKey)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			binding := ""
+			if !c.doubleSubmit {
+				binding = sessionBinding(ctx)
+			}
+
+			if binding == "" {
+				binding = csrfCookie(w, r)
+			}
+
+			if !isSafeMethod(r.Method) {
+				if !verifyCSRFToken(key, binding, submittedCSRFToken(r)) {
+					response.WriteError(ctx, w, http.StatusForbidden,
+						xerror.Enrich(errordef.ErrCSRFTokenInvalid, "missing or invalid csrf token"))
+					return
+				}
+			}
+
+			token := generateCSRFToken(key, binding)
+			w.Header().Set(CSRFHeaderName, token)
+
+			ctx = context.WithValue(ctx, csrfTokenKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// sessionBinding returns the value which tokens are bound to for the session
+// loaded by WithSessionStore. Unlike the session id, it does not change when
+// the session is saved or renewed, so that the tokens issued before remain
+// valid.
+func sessionBinding(ctx context.Context) string {
+	if val := ctx.Value(sessionBindingKey); val != nil {
+		return val.(string)
+	}
+
+	return ""
+}
+
+// csrfKey derives the key signing the tokens from the session authentication
+// key, so that every instance of a service accepts the same tokens. Without
+// it, the key is random and only valid in this process.
+func csrfKey(secret string) []byte {
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+
+		return key
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("csrf"))
+	return mac.Sum(nil)
+}
+
+// csrfCookie returns the double-submit cookie, and issues a new one if the
+// request has none.
+func csrfCookie(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		panic(err)
+	}
+
+	cookie := http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	http.SetCookie(w, &cookie)
+	return cookie.Value
+}
+
+func submittedCSRFToken(r *http.Request) string {
+	if token := r.Header.Get(CSRFHeaderName); token != "" {
+		return token
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == xhttp.ContentTypeXWWWFormUrlEncoded || mediaType == "multipart/form-data" {
+		return r.PostFormValue(CSRFFieldName)
+	}
+
+	return ""
+}
+
+// generateCSRFToken returns a random nonce followed by the MAC of the nonce
+// and the binding. The nonce changes the token on every response, which
+// protects it against compression side channels.
+func generateCSRFToken(key []byte, binding string) string {
+	nonce := make([]byte, csrfNonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(nonce, csrfMAC(key, binding, nonce)...))
+}
+
+func verifyCSRFToken(key []byte, binding string, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(decoded) <= csrfNonceLength {
+		return false
+	}
+
+	nonce, mac := decoded[:csrfNonceLength], decoded[csrfNonceLength:]
+	return hmac.Equal(mac, csrfMAC(key, binding, nonce))
+}
+
+func csrfMAC(key []byte, binding string, nonce []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(nonce)
+	mac.Write([]byte(binding))
+	return mac.Sum(nil)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,174 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sessionstore"
+	"github.com/todennus/shared/sharedtest"
+	"github.com/todennus/x/session"
+)
+
+func csrfServer(t *testing.T, c *config.Config, csrf *middleware.CSRF) http.Handler {
+	t.Helper()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if middleware.CSRFToken(r.Context()) == "" {
+			t.Error("csrf token is not in the context")
+		}
+	})
+
+	return middleware.SetupContext(c)(middleware.WithSessionStore(c)(csrf.Middleware(c)(handler)))
+}
+
+// issueCSRF sends a safe request and returns the issued token and cookies.
+func issueCSRF(t *testing.T, handler http.Handler, cookies ...*http.Cookie) (string, []*http.Cookie) {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	return w.Header().Get(middleware.CSRFHeaderName), w.Result().Cookies()
+}
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	handler := csrfServer(t, c, middleware.NewCSRF())
+
+	token, cookies := issueCSRF(t, handler)
+	if token == "" || len(cookies) != 1 || cookies[0].Name != middleware.CSRFCookieName {
+		t.Fatalf("token = %q, cookies = %v, want a token and the csrf cookie", token, cookies)
+	}
+
+	otherToken, otherCookies := issueCSRF(t, handler)
+
+	tests := []struct {
+		name    string
+		header  string
+		form    string
+		cookies []*http.Cookie
+		want    int
+	}{
+		{name: "header", header: token, cookies: cookies, want: http.StatusOK},
+		{name: "form field", form: token, cookies: cookies, want: http.StatusOK},
+		{name: "missing token", cookies: cookies, want: http.StatusForbidden},
+		{name: "missing cookie", header: token, want: http.StatusForbidden},
+		{name: "token of another cookie", header: otherToken, cookies: cookies, want: http.StatusForbidden},
+		{name: "cookie of another token", header: token, cookies: otherCookies, want: http.StatusForbidden},
+		{name: "tampered token", header: token[:len(token)-2] + "AA", cookies: cookies, want: http.StatusForbidden},
+		{name: "malformed token", header: "!!!", cookies: cookies, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r *http.Request
+			if tt.form != "" {
+				r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{middleware.CSRFFieldName: {tt.form}}.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				r = httptest.NewRequest(http.MethodPost, "/", nil)
+			}
+
+			if tt.header != "" {
+				r.Header.Set(middleware.CSRFHeaderName, tt.header)
+			}
+
+			for _, cookie := range tt.cookies {
+				r.AddCookie(cookie)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+
+			if tt.want == http.StatusForbidden {
+				sharedtest.AssertRESTError(t, w.Result(), http.StatusForbidden, errordef.ErrCSRFTokenInvalid)
+			}
+		})
+	}
+}
+
+func TestCSRFSessionBinding(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	backend := sessionstore.NewMemoryBackend(time.Hour, time.Hour)
+	c.SessionBackend = backend
+
+	newSession := func() *http.Cookie {
+		s := &session.Session{}
+		if err := backend.Save(context.Background(), s, map[any]any{}); err != nil {
+			t.Fatal(err)
+		}
+
+		return &http.Cookie{Name: session.SessionIDKey, Value: s.ID()}
+	}
+
+	tests := []struct {
+		name  string
+		other bool
+		want  int
+	}{
+		{name: "same session", want: http.StatusOK},
+		{name: "other session", other: true, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := csrfServer(t, c, middleware.NewCSRF())
+			sessionCookie := newSession()
+
+			token, cookies := issueCSRF(t, handler, sessionCookie)
+			if len(cookies) != 0 {
+				t.Errorf("cookies = %v, want no csrf cookie with a session", cookies)
+			}
+
+			if tt.other {
+				sessionCookie = newSession()
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Header.Set(middleware.CSRFHeaderName, token)
+			r.AddCookie(sessionCookie)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFTokenSurvivesRestart(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+
+	token, cookies := issueCSRF(t, csrfServer(t, c, middleware.NewCSRF()))
+
+	// Another instance with the same secret accepts the token.
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(middleware.CSRFHeaderName, token)
+	r.AddCookie(cookies[0])
+
+	w := httptest.NewRecorder()
+	csrfServer(t, c, middleware.NewCSRF()).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/idempotency"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xcontext"
+	"github.com/todennus/x/xerror"
+)
+
+// Replace this variable to change the header carrying the idempotency key.
+var IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotency stores the response of unsafe requests having an idempotency
+// key and replays it when the request is retried with the same key. Keys are
+// scoped by user, so this middleware must be placed after Authentication.
+// Server errors are not stored, the request can be retried after them. The
+// body is buffered to fingerprint the request, so it is bounded to the max body
+// size of the config.
+func Idempotency(config *config.Config, store idempotency.Store, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				response.WriteError(ctx, w, http.StatusBadRequest, xerror.Enrich(errordef.ErrRequestInvalid,
+					"%s must not exceed %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+				return
+			}
+
+			maxBytes := config.Variable.Server.MaxBodySize
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					response.WriteError(ctx, w, http.StatusRequestEntityTooLarge,
+						xerror.Enrich(errordef.ErrRequestTooLarge, "request body must not exceed %d bytes", maxBytes))
+					return
+				}
+
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.RESTWriteLogInvalidRequestError(ctx, w, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := fmt.Sprintf("ip:%s", remoteIP(r))
+			if uid := xcontext.RequestUserID(ctx); uid != 0 {
+				scope = fmt.Sprintf("user:%s", uid)
+			}
+
+			storeKey := fmt.Sprintf("idempotency:%s:%s", scope, key)
+			fingerprint := requestFingerprint(r, body)
+
+			record, reserved, err := store.Reserve(ctx, storeKey, fingerprint, ttl)
+			if err != nil {
+				xcontext.Logger(ctx).Warn("failed-to-reserve-idempotency-key", "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !reserved {
+				switch {
+				case record.Fingerprint != fingerprint:
+					response.WriteError(ctx, w, http.StatusConflict, xerror.Enrich(errordef.ErrIdempotencyConflict,
+						"%s has already been used by another request", IdempotencyKeyHeader))
+				case !record.Completed:
+					response.WriteError(ctx, w, http.StatusConflict, xerror.Enrich(errordef.ErrIdempotencyConflict,
+						"a request with the same %s is being processed", IdempotencyKeyHeader))
+				default:
+					replay(w, record)
+				}
+
+				return
+			}
+
+			// The outer headers, e.g. CORS or rate limit headers, are set again
+			// by the outer middlewares when the response is replayed.
+			rw := &recordWriter{ResponseWriter: w, status: http.StatusOK, outer: w.Header().Clone()}
+
+			// The key must be released or completed even if the request has
+			// timed out, otherwise it stays in progress until the ttl expires.
+			storeCtx := context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.Release(storeCtx, storeKey); err != nil {
+						xcontext.Logger(ctx).Warn("failed-to-release-idempotency-key", "err", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if rw.status >= http.StatusInternalServerError {
+				return
+			}
+
+			record = &idempotency.Record{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      rw.status,
+				Header:      rw.header,
+				Body:        rw.body.Bytes(),
+			}
+
+			if err := store.Complete(storeCtx, storeKey, record, ttl); err != nil {
+				xcontext.Logger(ctx).Warn("failed-to-store-idempotent-response", "err", err)
+				return
+			}
+
+			completed = true
+		})
+	}
+}
+
+// requestFingerprint identifies a request by its method, target and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte(" "))
+	hash.Write([]byte(r.URL.RequestURI()))
+	hash.Write([]byte("\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, record *idempotency.Record) {
+	header := w.Header()
+	for key, values := range record.Header {
+		header[key] = values
+	}
+
+	header.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// recordWriter records the response while writing it.
+type recordWriter struct {
+	http.ResponseWriter
+
+	status      int
+	outer       http.Header // The header before the handler is called.
+	header      http.Header // The header set by the handler.
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *recordWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = code
+
+		w.header = w.Header().Clone()
+		for key, values := range w.outer {
+			if slices.Equal(w.header[key], values) {
+				delete(w.header, key)
+			}
+		}
+
+		// Cookies belong to the first client only, they are not replayed.
+		w.header.Del("Set-Cookie")
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,296 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/idempotency"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sharedtest"
+)
+
+// contextStore fails like a remote store when the context is done.
+type contextStore struct {
+	idempotency.Store
+}
+
+func (s contextStore) Complete(ctx context.Context, key string, record *idempotency.Record, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Store.Complete(ctx, key, record, ttl)
+}
+
+func (s contextStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Store.Release(ctx, key)
+}
+
+type idempotencyRequest struct {
+	method     string
+	key        string
+	body       string
+	remoteAddr string
+}
+
+func (r idempotencyRequest) do(handler http.Handler) *httptest.ResponseRecorder {
+	method := r.method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req := httptest.NewRequest(method, "/orders", strings.NewReader(r.body))
+	if r.remoteAddr != "" {
+		req.RemoteAddr = r.remoteAddr
+	}
+
+	if r.key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, r.key)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// idempotencyServer counts the calls of its handler, which responds status.
+// An outer middleware sets a header which changes on every request.
+func idempotencyServer(c *config.Config, status int, calls *atomic.Int32) http.Handler {
+	requests := atomic.Int32{}
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(10-requests.Add(1))))
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "order", Value: "1"})
+		w.Header().Set("X-Order", strconv.Itoa(int(n)))
+		w.WriteHeader(status)
+		w.Write([]byte("order " + strconv.Itoa(int(n))))
+	})
+
+	store := idempotency.NewMemoryStore()
+	return middleware.SetupContext(c)(outer(middleware.Idempotency(c, store, time.Hour)(handler)))
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	calls := atomic.Int32{}
+	handler := idempotencyServer(c, http.StatusCreated, &calls)
+
+	first := idempotencyRequest{key: "key", body: "{}"}.do(handler)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", first.Code)
+	}
+
+	replayed := idempotencyRequest{key: "key", body: "{}"}.do(handler)
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != "order 1" {
+		t.Errorf("replayed = %d %q, want 201 %q", replayed.Code, replayed.Body.String(), "order 1")
+	}
+
+	header := replayed.Header()
+	if got := header.Get("Idempotent-Replayed"); got != "true" {
+		t.Errorf("Idempotent-Replayed = %q, want true", got)
+	}
+
+	if got := header.Get("X-Order"); got != "1" {
+		t.Errorf("X-Order = %q, want 1", got)
+	}
+
+	if got := header.Values("RateLimit-Remaining"); len(got) != 1 || got[0] != "8" {
+		t.Errorf("RateLimit-Remaining = %v, want [8], the outer headers must not be replayed", got)
+	}
+
+	if got := header.Values("Set-Cookie"); len(got) != 0 {
+		t.Errorf("Set-Cookie = %v, want none", got)
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		first     idempotencyRequest
+		second    idempotencyRequest
+		wantCalls int32
+		wantErr   error
+	}{
+		{
+			name:      "replayed",
+			status:    http.StatusOK,
+			first:     idempotencyRequest{key: "key", body: "a"},
+			second:    idempotencyRequest{key: "key", body: "a"},
+			wantCalls: 1,
+		},
+		{
+			name:      "other body",
+			status:    http.StatusOK,
+			first:     idempotencyRequest{key: "key", body: "a"},
+			second:    idempotencyRequest{key: "key", body: "b"},
+			wantCalls: 1,
+			wantErr:   errordef.ErrIdempotencyConflict,
+		},
+		{
+			name:      "other key",
+			status:    http.StatusOK,
+			first:     idempotencyRequest{key: "key", body: "a"},
+			second:    idempotencyRequest{key: "other", body: "a"},
+			wantCalls: 2,
+		},
+		{
+			name:      "other client",
+			status:    http.StatusOK,
+			first:     idempotencyRequest{key: "key", body: "a", remoteAddr: "10.0.0.1:1234"},
+			second:    idempotencyRequest{key: "key", body: "a", remoteAddr: "10.0.0.2:1234"},
+			wantCalls: 2,
+		},
+		{
+			name:      "without key",
+			status:    http.StatusOK,
+			first:     idempotencyRequest{body: "a"},
+			second:    idempotencyRequest{body: "a"},
+			wantCalls: 2,
+		},
+		{
+			name:      "safe method",
+			status:    http.StatusOK,
+			first:     idempotencyRequest{method: http.MethodGet, key: "key"},
+			second:    idempotencyRequest{method: http.MethodGet, key: "key"},
+			wantCalls: 2,
+		},
+		{
+			name:      "server error is not stored",
+			status:    http.StatusInternalServerError,
+			first:     idempotencyRequest{key: "key", body: "a"},
+			second:    idempotencyRequest{key: "key", body: "a"},
+			wantCalls: 2,
+		},
+		{
+			name:      "client error is stored",
+			status:    http.StatusBadRequest,
+			first:     idempotencyRequest{key: "key", body: "a"},
+			second:    idempotencyRequest{key: "key", body: "a"},
+			wantCalls: 1,
+		},
+		{
+			name:      "key too long",
+			status:    http.StatusOK,
+			second:    idempotencyRequest{key: strings.Repeat("k", 256), body: "a"},
+			wantCalls: 0,
+			wantErr:   errordef.ErrRequestInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := sharedtest.NewConfig(t)
+			calls := atomic.Int32{}
+			handler := idempotencyServer(c, tt.status, &calls)
+
+			if tt.first != (idempotencyRequest{}) {
+				tt.first.do(handler)
+			}
+
+			w := tt.second.do(handler)
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls.Load(), tt.wantCalls)
+			}
+
+			if tt.wantErr != nil {
+				code := http.StatusConflict
+				if errors.Is(tt.wantErr, errordef.ErrRequestInvalid) {
+					code = http.StatusBadRequest
+				}
+
+				sharedtest.AssertRESTError(t, w.Result(), code, tt.wantErr)
+			} else if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	started, done := make(chan struct{}), make(chan struct{})
+	handler := middleware.SetupContext(c)(middleware.Idempotency(c, idempotency.NewMemoryStore(), time.Hour)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-done
+		}),
+	))
+
+	go idempotencyRequest{key: "key", body: "a"}.do(handler)
+	<-started
+
+	w := idempotencyRequest{key: "key", body: "a"}.do(handler)
+	close(done)
+
+	sharedtest.AssertRESTError(t, w.Result(), http.StatusConflict, errordef.ErrIdempotencyConflict)
+}
+
+func TestIdempotencyTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantCode int
+	}{
+		// The key is released, the request can be retried.
+		{name: "released", status: http.StatusServiceUnavailable, wantCode: http.StatusServiceUnavailable},
+		// The response is stored, it is replayed.
+		{name: "completed", status: http.StatusAccepted, wantCode: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := sharedtest.NewConfig(t)
+			c.Variable.Server.RequestTimeout = 10
+
+			calls := atomic.Int32{}
+			store := contextStore{Store: idempotency.NewMemoryStore()}
+			handler := middleware.SetupContext(c)(middleware.Timeout(c)(middleware.Idempotency(c, store, time.Hour)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls.Add(1)
+					<-r.Context().Done()
+					w.WriteHeader(tt.status)
+				}),
+			)))
+
+			idempotencyRequest{key: "key", body: "a"}.do(handler)
+			w := idempotencyRequest{key: "key", body: "a"}.do(handler)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			wantCalls := int32(1)
+			if tt.status >= http.StatusInternalServerError {
+				wantCalls = 2
+			}
+
+			if calls.Load() != wantCalls {
+				t.Errorf("calls = %d, want %d", calls.Load(), wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todennus/shared/metrics"
+)
+
+// Metrics records the number, latency and in-flight count of requests, by the
+// route of chi or http.ServeMux, or "unmatched".
+func Metrics() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlightRoute := matchRoute(r)
+			if inFlightRoute == "" {
+				inFlightRoute = "unmatched"
+			}
+
+			inFlight := metrics.HTTPRequestsInFlight.WithLabelValues(inFlightRoute, r.Method)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			rw := newResponseWriter(w)
+
+			start := time.Now()
+			next.ServeHTTP(rw, r)
+			rtt := time.Since(start)
+
+			route := routePattern(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			status := strconv.Itoa(rw.status)
+			metrics.HTTPRequests.WithLabelValues(route, r.Method, status, rw.errorCode).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(route, r.Method, status).Observe(rtt.Seconds())
+		})
+	}
+}
+
+// matchRoute resolves the route pattern of a request before it is served.
+func matchRoute(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return r.Pattern
+	}
+
+	// Routes is the top-level router even inside a mounted sub-router, so
+	// the full path is matched.
+	path := r.URL.Path
+	if r.URL.RawPath != "" {
+		path = r.URL.RawPath
+	}
+
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, path) {
+		return ""
+	}
+
+	return tctx.RoutePattern()
+}
@@ -0,0 +1,44 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sharedtest"
+)
+
+func TestWritersFlush(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+	}{
+		{name: "metrics", middleware: middleware.Metrics()},
+		{name: "access log", middleware: middleware.AccessLog(c)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				flusher, ok := w.(http.Flusher)
+				if !ok {
+					t.Fatal("writer is not a http.Flusher")
+				}
+
+				w.Write([]byte("data"))
+				flusher.Flush()
+			}))
+
+			handler = middleware.SetupContext(c)(handler)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if !w.Flushed {
+				t.Error("response is not flushed")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/ratelimit"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xcontext"
+	"github.com/todennus/x/xerror"
+)
+
+// RateLimit rejects requests exceeding the policies of limiter. It must be
+// placed after Authentication to limit by user or client. The client id claimed
+// by an unauthenticated request cannot be trusted, such requests are only
+// limited by their remote address.
+func RateLimit(limiter *ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			identities := map[ratelimit.By]string{}
+			for _, by := range limiter.Policies() {
+				switch by {
+				case ratelimit.ByUser:
+					if uid := xcontext.RequestUserID(ctx); uid != 0 {
+						identities[by] = uid.String()
+					}
+				case ratelimit.ByClient:
+					identities[by] = authn.RequestClientID(ctx)
+				case ratelimit.ByIP:
+					identities[by] = remoteIP(r)
+				}
+			}
+
+			result, err := limiter.Allow(ctx, identities)
+			if err != nil {
+				xcontext.Logger(ctx).Warn("failed-to-check-rate-limit", "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if result.Limit > 0 {
+				for key, value := range result.Headers() {
+					w.Header().Set(key, value)
+				}
+			}
+
+			if !result.Allowed {
+				response.WriteError(ctx, w, http.StatusTooManyRequests,
+					xerror.Enrich(errordef.ErrRateLimitExceeded, "too many requests, retry after %s second(s)", result.Headers()["Retry-After"]))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
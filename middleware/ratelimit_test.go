@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/ratelimit"
+	"github.com/todennus/shared/sharedtest"
+)
+
+func TestRateLimit(t *testing.T) {
+	once := ratelimit.Policy{Limit: 1, Period: time.Minute}
+
+	tests := []struct {
+		name       string
+		by         ratelimit.By
+		wantSecond int
+	}{
+		// Unauthenticated requests have no client id, they are only limited
+		// by ip.
+		{name: "client", by: ratelimit.ByClient, wantSecond: http.StatusOK},
+		{name: "ip", by: ratelimit.ByIP, wantSecond: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := sharedtest.NewConfig(t)
+			limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore()).WithPolicy(tt.by, once)
+
+			handler := middleware.SetupContext(c)(middleware.RateLimit(limiter)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+			for i, want := range []int{http.StatusOK, tt.wantSecond} {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+				if w.Code != want {
+					t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, want)
+				}
+
+				if want == http.StatusTooManyRequests {
+					sharedtest.AssertRESTError(t, w.Result(), want, errordef.ErrRateLimitExceeded)
+					if w.Header().Get("Retry-After") == "" {
+						t.Error("Retry-After is not set")
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/x/session"
+)
+
+// CSPNoncePlaceholder is replaced by the nonce of the request in
+// SecurityHeaders.ContentSecurityPolicy.
+const CSPNoncePlaceholder = "{nonce}"
+
+// CSPNonce returns the nonce allowed by the Content-Security-Policy of the
+// request, templates use it in the nonce attribute of inline scripts and
+// styles.
+func CSPNonce(ctx context.Context) string {
+	if val := ctx.Value(cspNonceKey); val != nil {
+		return val.(string)
+	}
+
+	return ""
+}
+
+// SecurityHeaders describes the security headers added to responses. A zero
+// field disables the corresponding header.
+type SecurityHeaders struct {
+	// Strict-Transport-Security.
+	HSTSMaxAge            int // in second
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	// X-Content-Type-Options: nosniff.
+	ContentTypeNosniff bool
+
+	ReferrerPolicy string
+
+	// FrameAncestors is added to the Content-Security-Policy as the
+	// frame-ancestors directive. The legacy X-Frame-Options is derived from
+	// 'none' and 'self'.
+	FrameAncestors []string
+
+	// ContentSecurityPolicy may contain CSPNoncePlaceholder, a new nonce is
+	// generated for every request in this case.
+	ContentSecurityPolicy string
+
+	// NoStore forbids caching of the responses. It is always applied to
+	// requests carrying credentials, i.e. an Authorization header or a
+	// session cookie, and to requests whose principal is authenticated.
+	NoStore bool
+}
+
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		HSTSMaxAge:            365 * 24 * 60 * 60, // 1y
+		HSTSIncludeSubdomains: true,
+		ContentTypeNosniff:    true,
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		FrameAncestors:        []string{"'none'"},
+		ContentSecurityPolicy: "default-src 'self'; " +
+			"script-src 'self' 'nonce-" + CSPNoncePlaceholder + "'; " +
+			"style-src 'self' 'nonce-" + CSPNoncePlaceholder + "'; " +
+			"img-src 'self' data:; object-src 'none'; base-uri 'self'",
+	}
+}
+
+// Middleware adds the security headers to every response. To override the
+// headers of some routes, mount the middleware of another SecurityHeaders on
+// these routes. The inner one replaces every header it sets and keeps the
+// nonce of the outer one.
+func (h SecurityHeaders) Middleware() func(next http.Handler) http.Handler {
+	hsts := ""
+	if h.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", h.HSTSMaxAge)
+		if h.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if h.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	csp := h.ContentSecurityPolicy
+	if len(h.FrameAncestors) > 0 {
+		directive := "frame-ancestors " + strings.Join(h.FrameAncestors, " ")
+		if csp == "" {
+			csp = directive
+		} else {
+			csp = strings.TrimSuffix(strings.TrimSpace(csp), ";") + "; " + directive
+		}
+	}
+
+	frameOptions := ""
+	if len(h.FrameAncestors) == 1 {
+		switch h.FrameAncestors[0] {
+		case "'none'":
+			frameOptions = "DENY"
+		case "'self'":
+			frameOptions = "SAMEORIGIN"
+		}
+	}
+
+	useNonce := strings.Contains(csp, CSPNoncePlaceholder)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			header := w.Header()
+
+			if hsts != "" {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+
+			if h.ContentTypeNosniff {
+				header.Set("X-Content-Type-Options", "nosniff")
+			}
+
+			if h.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", h.ReferrerPolicy)
+			}
+
+			if frameOptions != "" {
+				header.Set("X-Frame-Options", frameOptions)
+			}
+
+			if csp != "" {
+				policy := csp
+				if useNonce {
+					nonce := CSPNonce(ctx)
+					if nonce == "" {
+						nonce = newCSPNonce()
+						ctx = context.WithValue(ctx, cspNonceKey, nonce)
+					}
+
+					policy = strings.ReplaceAll(policy, CSPNoncePlaceholder, nonce)
+				}
+
+				header.Set("Content-Security-Policy", policy)
+			}
+
+			if h.NoStore || hasCredentials(r) {
+				header.Set("Cache-Control", "no-store")
+				header.Set("Pragma", "no-cache")
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// hasCredentials reports whether the response may depend on the identity of
+// the requester, so that it must not be cached.
+func hasCredentials(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" || authn.RequestPrincipal(r.Context()) != nil {
+		return true
+	}
+
+	cookie, err := r.Cookie(session.SessionIDKey)
+	return err == nil && cookie.Value != ""
+}
+
+func newCSPNonce() string {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(nonce)
+}
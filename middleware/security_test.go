@@ -0,0 +1,128 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/x/session"
+)
+
+func TestSecurityHeadersDefault(t *testing.T) {
+	var nonce string
+	handler := middleware.DefaultSecurityHeaders().Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = middleware.CSPNonce(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if nonce == "" {
+		t.Fatal("nonce is not in the context")
+	}
+
+	want := map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Content-Type-Options":    "nosniff",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"X-Frame-Options":           "DENY",
+		"Cache-Control":             "",
+	}
+
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	csp := w.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "'nonce-"+nonce+"'") || strings.Contains(csp, middleware.CSPNoncePlaceholder) {
+		t.Errorf("csp = %q, want the nonce %q", csp, nonce)
+	}
+
+	if !strings.HasSuffix(csp, "; frame-ancestors 'none'") {
+		t.Errorf("csp = %q, want the frame-ancestors directive", csp)
+	}
+}
+
+func TestSecurityHeadersNonceChanges(t *testing.T) {
+	handler := middleware.DefaultSecurityHeaders().Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	policies := map[string]bool{}
+	for range 3 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		policies[w.Header().Get("Content-Security-Policy")] = true
+	}
+
+	if len(policies) != 3 {
+		t.Errorf("got %d distinct policies, want a new nonce per request", len(policies))
+	}
+}
+
+func TestSecurityHeadersOverride(t *testing.T) {
+	var innerNonce string
+	inner := middleware.SecurityHeaders{
+		FrameAncestors:        []string{"'self'"},
+		ContentSecurityPolicy: "script-src 'nonce-" + middleware.CSPNoncePlaceholder + "'",
+	}
+
+	innerHandler := inner.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		innerNonce = middleware.CSPNonce(r.Context())
+	}))
+
+	var outerNonce string
+	handler := middleware.DefaultSecurityHeaders().Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outerNonce = middleware.CSPNonce(r.Context())
+		innerHandler.ServeHTTP(w, r)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if innerNonce != outerNonce {
+		t.Errorf("inner nonce = %q, want the outer nonce %q", innerNonce, outerNonce)
+	}
+
+	if got := w.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q, want SAMEORIGIN", got)
+	}
+
+	if want := "script-src 'nonce-" + outerNonce + "'; frame-ancestors 'self'"; w.Header().Get("Content-Security-Policy") != want {
+		t.Errorf("csp = %q, want %q", w.Header().Get("Content-Security-Policy"), want)
+	}
+}
+
+func TestSecurityHeadersNoStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		noStore bool
+		setup   func(r *http.Request)
+		want    string
+	}{
+		{name: "anonymous", want: ""},
+		{name: "no store", noStore: true, want: "no-store"},
+		{name: "authorization", setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer x") }, want: "no-store"},
+		{name: "session cookie", setup: func(r *http.Request) { r.AddCookie(&http.Cookie{Name: session.SessionIDKey, Value: "id"}) }, want: "no-store"},
+		{name: "empty session cookie", setup: func(r *http.Request) { r.AddCookie(&http.Cookie{Name: session.SessionIDKey, Value: ""}) }, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.SecurityHeaders{NoStore: tt.noStore}.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.setup != nil {
+				tt.setup(r)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
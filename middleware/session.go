@@ -1,25 +1,81 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/sessionstore"
 	"github.com/todennus/x/session"
 	"github.com/todennus/x/xcontext"
 )
 
+// WithSession loads the session cookie into the context. The session is not
+// verified, see WithSessionStore.
 func WithSession(manager *session.Manager) func(http.Handler) http.Handler {
+	return withSession(manager, nil)
+}
+
+// WithSessionStore is the same as WithSession, with the session manager of
+// the config. If the config has a session backend, the session is verified
+// and its idle timeout is renewed. An expired or tampered session is replaced
+// by an empty one and its cookie is cleared.
+func WithSessionStore(config *config.Config) func(http.Handler) http.Handler {
+	return withSession(config.SessionManager, config.SessionBackend)
+}
+
+func withSession(manager *session.Manager, backend sessionstore.Backend) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
 
-			session, err := manager.Get(r)
+			s, err := manager.Get(r)
 			if err != nil {
 				xcontext.Logger(ctx).Debug("failed-to-get-cookie", "err", err)
-			} else {
-				ctx = xcontext.WithSession(ctx, session)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if backend != nil && s.ID() != "" {
+				data, err := backend.Load(ctx, s)
+				switch {
+				case err == nil:
+					if err := backend.Renew(ctx, s); err != nil {
+						xcontext.Logger(ctx).Warn("failed-to-renew-session", "err", err)
+					}
+
+					if binding := sessionstore.Binding(data); binding != "" {
+						ctx = context.WithValue(ctx, sessionBindingKey, binding)
+					}
+
+				case errors.Is(err, sessionstore.ErrSessionExpired):
+					xcontext.Logger(ctx).Debug("session-expired")
+					s = clearSession(w)
+
+				default:
+					xcontext.Logger(ctx).Warn("invalid-session-cookie", "err", err, "rip", r.RemoteAddr)
+					s = clearSession(w)
+				}
 			}
 
+			ctx = xcontext.WithSession(ctx, s)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// clearSession expires the session cookie and returns an empty session.
+func clearSession(w http.ResponseWriter) *session.Session {
+	http.SetCookie(w, &http.Cookie{
+		Name:     session.SessionIDKey,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return &session.Session{}
+}
@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/sessionstore"
+	"github.com/todennus/shared/sharedtest"
+	"github.com/todennus/x/session"
+	"github.com/todennus/x/xcontext"
+)
+
+func TestWithSessionStore(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	backend := sessionstore.NewMemoryBackend(time.Hour, time.Hour)
+	c.SessionBackend = backend
+
+	newSession := func() string {
+		s := &session.Session{}
+		if err := backend.Save(context.Background(), s, map[any]any{}); err != nil {
+			t.Fatal(err)
+		}
+
+		return s.ID()
+	}
+
+	valid := newSession()
+
+	revoked := newSession()
+	s := &session.Session{}
+	s.SetID(revoked)
+	if err := backend.Revoke(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cookie  string
+		wantID  string
+		cleared bool
+	}{
+		{name: "no cookie"},
+		{name: "valid", cookie: valid, wantID: valid},
+		{name: "expired", cookie: revoked, cleared: true},
+		{name: "tampered", cookie: valid[:len(valid)-1] + "x", cleared: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID string
+			handler := middleware.SetupContext(c)(middleware.WithSessionStore(c)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					gotID = xcontext.Session(r.Context()).ID()
+				}),
+			))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: session.SessionIDKey, Value: tt.cookie})
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if gotID != tt.wantID {
+				t.Errorf("session id = %q, want %q", gotID, tt.wantID)
+			}
+
+			cleared := false
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.Name == session.SessionIDKey && cookie.MaxAge < 0 {
+					cleared = true
+				}
+			}
+
+			if cleared != tt.cleared {
+				t.Errorf("cookie cleared = %v, want %v", cleared, tt.cleared)
+			}
+		})
+	}
+}
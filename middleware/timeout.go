@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/todennus/shared/config"
 	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/metrics"
 )
 
 func Timeout(config *config.Config) func(next http.Handler) http.Handler {
@@ -20,6 +22,10 @@ func Timeout(config *config.Config) func(next http.Handler) http.Handler {
 			defer cancel()
 
 			next.ServeHTTP(w, r.WithContext(ctx))
+
+			if errors.Is(context.Cause(ctx), errordef.ErrServerTimeout) {
+				metrics.Timeouts.WithLabelValues("http").Inc()
+			}
 		})
 	}
 }
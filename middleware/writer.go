@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// responseWriter records the status code, the number of written bytes and the
+// error code of the response passed through it.
+type responseWriter struct {
+	http.ResponseWriter
+
+	status      int
+	bytes       int
+	errorCode   string
+	wroteHeader bool
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *responseWriter) SetErrorCode(code string) {
+	w.errorCode = code
+}
+
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
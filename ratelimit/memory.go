@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// sweepInterval is the minimum time between two cleanups of the buckets.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	policy  Policy
+	tokens  float64
+	updated time.Time
+}
+
+// refill returns the time needed for the bucket to be full again.
+func (b *bucket) refill() time.Duration {
+	return secondsToDuration(b.policy.capacity() / b.policy.rate())
+}
+
+// MemoryStore keeps buckets in the process memory. It is suitable for tests
+// and single-instance deployments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweep   time.Time
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), sweep: time.Now(), now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, buckets []Bucket) ([]Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.cleanup(now)
+
+	states := make([]*bucket, len(buckets))
+	allowed := true
+	for i, ref := range buckets {
+		b, ok := s.buckets[ref.Key]
+		if !ok {
+			b = &bucket{tokens: ref.Policy.capacity(), updated: now}
+			s.buckets[ref.Key] = b
+		}
+
+		b.policy = ref.Policy
+		b.tokens = math.Min(ref.Policy.capacity(), b.tokens+now.Sub(b.updated).Seconds()*ref.Policy.rate())
+		b.updated = now
+
+		allowed = allowed && b.tokens >= 1
+		states[i] = b
+	}
+
+	results := make([]Result, len(buckets))
+	for i, b := range states {
+		hasToken := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+
+		results[i] = newResult(b.policy, hasToken, b.tokens)
+	}
+
+	return results, nil
+}
+
+// cleanup removes buckets which have been full for a while, each according
+// to its own policy.
+func (s *MemoryStore) cleanup(now time.Time) {
+	if now.Sub(s.sweep) < sweepInterval {
+		return
+	}
+
+	s.sweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > b.refill() {
+			delete(s.buckets, key)
+		}
+	}
+}
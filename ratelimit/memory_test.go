@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// clock is a time source which only moves when advanced.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{now: time.Unix(1_700_000_000, 0)}
+	store := NewMemoryStore()
+	store.now = c.Now
+	store.sweep = c.now
+	return store, c
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	// 10 tokens per 10 seconds, i.e. 1 token per second, at most 3 tokens.
+	policy := Policy{Limit: 10, Period: 10 * time.Second, Burst: 3}
+
+	tests := []struct {
+		name  string
+		takes int
+		wait  time.Duration
+
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+		wantResetAfter time.Duration
+	}{
+		{name: "first take", takes: 0, wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+		{name: "last token", takes: 2, wantAllowed: true, wantRemaining: 0, wantResetAfter: 3 * time.Second},
+		{name: "empty bucket", takes: 3, wantAllowed: false, wantRemaining: 0, wantRetryAfter: time.Second, wantResetAfter: 3 * time.Second},
+		{name: "half refilled", takes: 3, wait: 500 * time.Millisecond, wantAllowed: false, wantRemaining: 0, wantRetryAfter: 500 * time.Millisecond, wantResetAfter: 2500 * time.Millisecond},
+		{name: "one refilled", takes: 3, wait: time.Second, wantAllowed: true, wantRemaining: 0, wantResetAfter: 3 * time.Second},
+		{name: "capped at burst", takes: 3, wait: time.Hour, wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, clock := newTestStore()
+			buckets := []Bucket{{Key: "k", Policy: policy}}
+
+			for range tt.takes {
+				if _, err := store.Take(context.Background(), buckets); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			clock.now = clock.now.Add(tt.wait)
+			results, err := store.Take(context.Background(), buckets)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := results[0]
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+
+			if got.Limit != 3 {
+				t.Errorf("limit = %d, want 3", got.Limit)
+			}
+
+			if got.Remaining != tt.wantRemaining {
+				t.Errorf("remaining = %d, want %d", got.Remaining, tt.wantRemaining)
+			}
+
+			if got.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("retry after = %v, want %v", got.RetryAfter, tt.wantRetryAfter)
+			}
+
+			if got.ResetAfter != tt.wantResetAfter {
+				t.Errorf("reset after = %v, want %v", got.ResetAfter, tt.wantResetAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTakesAllOrNothing(t *testing.T) {
+	store, _ := newTestStore()
+
+	wide := Bucket{Key: "wide", Policy: Policy{Limit: 10, Period: time.Minute}}
+	narrow := Bucket{Key: "narrow", Policy: Policy{Limit: 1, Period: time.Minute}}
+
+	results, err := store.Take(context.Background(), []Bucket{wide, narrow})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !results[0].Allowed || !results[1].Allowed {
+		t.Fatalf("results = %+v, want both allowed", results)
+	}
+
+	// The narrow bucket is empty, the wide bucket must not be consumed.
+	for range 5 {
+		results, err = store.Take(context.Background(), []Bucket{wide, narrow})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if results[1].Allowed {
+			t.Fatalf("narrow result = %+v, want rejected", results[1])
+		}
+	}
+
+	if results[0].Remaining != 9 {
+		t.Errorf("wide remaining = %d, want 9 after rejected takes", results[0].Remaining)
+	}
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	store, clock := newTestStore()
+
+	// The slow bucket needs 10 minutes to refill, the fast one a second.
+	slow := Bucket{Key: "slow", Policy: Policy{Limit: 1, Period: 10 * time.Minute}}
+	fast := Bucket{Key: "fast", Policy: Policy{Limit: 1, Period: time.Second}}
+	if _, err := store.Take(context.Background(), []Bucket{slow, fast}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.now = clock.now.Add(2 * sweepInterval)
+	store.cleanup(clock.now)
+
+	if _, ok := store.buckets["fast"]; ok {
+		t.Error("fast bucket is kept, want removed once refilled")
+	}
+
+	if _, ok := store.buckets["slow"]; !ok {
+		t.Error("slow bucket is removed before it is refilled")
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	store, _ := newTestStore()
+	limiter := NewLimiter(store).
+		WithPolicy(ByUser, Policy{Limit: 5, Period: time.Minute}).
+		WithPolicy(ByIP, Policy{Limit: 2, Period: time.Minute})
+
+	tests := []struct {
+		name          string
+		identities    map[By]string
+		wantAllowed   bool
+		wantLimit     int
+		wantRemaining int
+	}{
+		{name: "no identity", identities: map[By]string{}, wantAllowed: true},
+		{name: "disabled policy", identities: map[By]string{ByClient: "client"}, wantAllowed: true},
+		{name: "most restrictive", identities: map[By]string{ByUser: "1", ByIP: "10.0.0.1"}, wantAllowed: true, wantLimit: 2, wantRemaining: 1},
+		{name: "last token", identities: map[By]string{ByUser: "1", ByIP: "10.0.0.1"}, wantAllowed: true, wantLimit: 2, wantRemaining: 0},
+		{name: "rejected", identities: map[By]string{ByUser: "1", ByIP: "10.0.0.1"}, wantAllowed: false, wantLimit: 2, wantRemaining: 0},
+		{name: "other ip", identities: map[By]string{ByUser: "1", ByIP: "10.0.0.2"}, wantAllowed: true, wantLimit: 2, wantRemaining: 1},
+	}
+
+	for _, tt := range tests {
+		result, err := limiter.Allow(context.Background(), tt.identities)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if result.Allowed != tt.wantAllowed || result.Limit != tt.wantLimit || result.Remaining != tt.wantRemaining {
+			t.Errorf("%s: result = %+v, want allowed %v, limit %d, remaining %d",
+				tt.name, result, tt.wantAllowed, tt.wantLimit, tt.wantRemaining)
+		}
+	}
+}
+
+func TestResultHeaders(t *testing.T) {
+	result := Result{Allowed: false, Limit: 10, Remaining: 0, ResetAfter: 1500 * time.Millisecond, RetryAfter: 200 * time.Millisecond}
+
+	want := map[string]string{
+		"RateLimit-Limit":     "10",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "2",
+		"Retry-After":         "1",
+	}
+
+	got := result.Headers()
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/todennus/shared/config"
+)
+
+// By is the kind of identity which a policy is applied to.
+type By int
+
+const (
+	ByUser By = iota + 1
+	ByClient
+	ByIP
+)
+
+func (by By) String() string {
+	switch by {
+	case ByUser:
+		return "user"
+	case ByClient:
+		return "client"
+	case ByIP:
+		return "ip"
+	default:
+		return "unknown"
+	}
+}
+
+// Policy is a token bucket which is refilled with Limit tokens every Period
+// and holds at most Burst tokens.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+func PolicyFromConfig(variable config.RateLimitPolicyVariable) Policy {
+	return Policy{
+		Limit:  variable.Limit,
+		Period: time.Duration(variable.Period) * time.Second,
+		Burst:  variable.Burst,
+	}
+}
+
+func (p Policy) capacity() float64 {
+	if p.Burst <= 0 {
+		return float64(p.Limit)
+	}
+
+	return float64(p.Burst)
+}
+
+// rate returns the number of tokens refilled per second.
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+func (p Policy) enabled() bool {
+	return p.Limit > 0 && p.Period > 0
+}
+
+// Result describes the state of a bucket after a request has been taken.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+
+	// RetryAfter is the time until the next request is allowed. It is zero
+	// if the request is allowed.
+	RetryAfter time.Duration
+}
+
+func newResult(policy Policy, allowed bool, tokens float64) Result {
+	rate := policy.rate()
+	result := Result{
+		Allowed:    allowed,
+		Limit:      int(policy.capacity()),
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: secondsToDuration((policy.capacity() - tokens) / rate),
+	}
+
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// Bucket identifies a bucket and the policy which it follows.
+type Bucket struct {
+	Key    string
+	Policy Policy
+}
+
+// Store keeps the state of buckets.
+type Store interface {
+	// Take removes a token from every bucket if all of them have one.
+	// Otherwise, no token is taken, so that a request rejected by a bucket
+	// does not consume the others. The results are in the order of buckets.
+	Take(ctx context.Context, buckets []Bucket) ([]Result, error)
+}
+
+type Limiter struct {
+	store    Store
+	policies map[By]Policy
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store, policies: make(map[By]Policy)}
+}
+
+// NewLimiterFromConfig creates a limiter with the user, client and ip policies
+// of the configuration.
+func NewLimiterFromConfig(store Store, variable config.RateLimitVariable) *Limiter {
+	return NewLimiter(store).
+		WithPolicy(ByUser, PolicyFromConfig(variable.User)).
+		WithPolicy(ByClient, PolicyFromConfig(variable.Client)).
+		WithPolicy(ByIP, PolicyFromConfig(variable.IP))
+}
+
+func (l *Limiter) WithPolicy(by By, policy Policy) *Limiter {
+	if policy.enabled() {
+		l.policies[by] = policy
+	} else {
+		delete(l.policies, by)
+	}
+
+	return l
+}
+
+// Policies returns the kinds of identity which are limited.
+func (l *Limiter) Policies() []By {
+	result := []By{}
+	for _, by := range []By{ByUser, ByClient, ByIP} {
+		if _, ok := l.policies[by]; ok {
+			result = append(result, by)
+		}
+	}
+
+	return result
+}
+
+// Allow takes a token from the bucket of every known identity, unless one of
+// them rejects the request, and returns the most restrictive result. If no
+// policy applies, the result is allowed and its Limit is zero.
+func (l *Limiter) Allow(ctx context.Context, identities map[By]string) (Result, error) {
+	buckets := []Bucket{}
+	for _, by := range l.Policies() {
+		identity := identities[by]
+		if identity == "" {
+			continue
+		}
+
+		buckets = append(buckets, Bucket{
+			Key:    fmt.Sprintf("ratelimit:%s:%s", by, identity),
+			Policy: l.policies[by],
+		})
+	}
+
+	if len(buckets) == 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	results, err := l.store.Take(ctx, buckets)
+	if err != nil {
+		return Result{}, err
+	}
+
+	final := results[0]
+	for _, result := range results[1:] {
+		if moreRestrictive(result, final) {
+			final = result
+		}
+	}
+
+	return final, nil
+}
+
+func moreRestrictive(a, b Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+
+	return a.Remaining < b.Remaining
+}
+
+// Headers returns the RateLimit-* headers describing the result, plus
+// Retry-After if the request is rejected.
+func (r Result) Headers() map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(r.Limit),
+		"RateLimit-Remaining": strconv.Itoa(r.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(ceilSeconds(r.ResetAfter)),
+	}
+
+	if !r.Allowed {
+		headers["Retry-After"] = strconv.Itoa(ceilSeconds(r.RetryAfter))
+	}
+
+	return headers
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Store = (*RedisStore)(nil)
+
+// takeScript refills every bucket, then takes a token from each of them if
+// all have one, atomically. ARGV holds the capacity and rate of each key. The
+// tokens are returned as strings because Redis truncates Lua numbers to
+// integers.
+var takeScript = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local tokens = {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 2 - 1])
+	local rate = tonumber(ARGV[i * 2])
+
+	local state = redis.call("HMGET", key, "tokens", "updated")
+	local current = tonumber(state[1])
+	local updated = tonumber(state[2])
+	if current == nil or updated == nil then
+		current = capacity
+		updated = now
+	end
+
+	tokens[i] = math.min(capacity, current + (now - updated) * rate)
+	if tokens[i] < 1 then
+		allowed = 0
+	end
+end
+
+local result = {}
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 2 - 1])
+	local rate = tonumber(ARGV[i * 2])
+
+	local hasToken = 0
+	if tokens[i] >= 1 then
+		hasToken = 1
+	end
+
+	if allowed == 1 then
+		tokens[i] = tokens[i] - 1
+	end
+
+	redis.call("HSET", key, "tokens", tostring(tokens[i]), "updated", now)
+	redis.call("PEXPIRE", key, math.ceil(capacity / rate))
+
+	table.insert(result, hasToken)
+	table.insert(result, tostring(tokens[i]))
+end
+
+return result
+`)
+
+// RedisStore keeps buckets in Redis, so that the limit is shared between
+// every instance of a service.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Take(ctx context.Context, buckets []Bucket) ([]Result, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]any, 0, 2*len(buckets))
+	for _, bucket := range buckets {
+		// The script works with milliseconds.
+		keys = append(keys, bucket.Key)
+		args = append(args,
+			strconv.FormatFloat(bucket.Policy.capacity(), 'f', -1, 64),
+			strconv.FormatFloat(bucket.Policy.rate()/1000, 'f', -1, 64),
+		)
+	}
+
+	values, err := takeScript.Run(ctx, s.client, keys, args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 2*len(buckets) {
+		return nil, fmt.Errorf("unexpected result of rate limit script: %v", values)
+	}
+
+	results := make([]Result, len(buckets))
+	for i, bucket := range buckets {
+		hasToken, _ := values[2*i].(int64)
+		tokensStr, _ := values[2*i+1].(string)
+		tokens, err := strconv.ParseFloat(tokensStr, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = newResult(bucket.Policy, hasToken == 1, tokens)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,231 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/x/xerror"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// RequestIDMetadataKey is the metadata carrying the request id from the
+// gateway, or a calling service, to the gRPC server.
+const RequestIDMetadataKey = "x-request-id"
+
+const (
+	gatewayRedirectLocationKey = "x-gateway-redirect-location"
+	gatewayRedirectCodeKey     = "x-gateway-redirect-code"
+)
+
+// gatewayHeaders are the header metadata forwarded by the gateway as plain
+// HTTP headers, the same as the native REST handlers write them.
+var gatewayHeaders = map[string]bool{
+	"retry-after":         true,
+	"ratelimit-limit":     true,
+	"ratelimit-remaining": true,
+	"ratelimit-reset":     true,
+}
+
+// gatewayErrors are the errors of the statuses which have no registered code,
+// e.g. the statuses created by the gateway itself or by another library.
+var gatewayErrors = map[codes.Code]error{
+	codes.InvalidArgument:   errordef.ErrRequestInvalid,
+	codes.NotFound:          errordef.ErrNotFound,
+	codes.AlreadyExists:     errordef.ErrDuplicated,
+	codes.Unauthenticated:   errordef.ErrUnauthenticated,
+	codes.PermissionDenied:  errordef.ErrForbidden,
+	codes.ResourceExhausted: errordef.ErrRateLimitExceeded,
+	codes.DeadlineExceeded:  errordef.ErrServerTimeout,
+}
+
+// GatewayServeMuxOptions returns the options making a gateway ServeMux respond
+// like the native REST handlers. The mux must be served behind the same
+// middlewares, at least middleware.SetupContext.
+func GatewayServeMuxOptions() []runtime.ServeMuxOption {
+	return []runtime.ServeMuxOption{
+		runtime.WithErrorHandler(GatewayErrorHandler),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, NewGatewayMarshaler()),
+		runtime.WithForwardResponseOption(GatewayForwardResponse),
+		runtime.WithOutgoingHeaderMatcher(GatewayOutgoingHeaderMatcher),
+		runtime.WithMetadata(GatewayMetadata),
+	}
+}
+
+// GatewayErrorHandler renders the status returned by the gRPC server into a
+// RESTResponse, with the HTTP status the native REST handlers use for the same
+// error. It also writes the redirect returned by GatewayForwardResponse.
+func GatewayErrorHandler(
+	ctx context.Context,
+	_ *runtime.ServeMux,
+	_ runtime.Marshaler,
+	w http.ResponseWriter,
+	_ *http.Request,
+	err error,
+) {
+	var redirect *gatewayRedirect
+	if errors.As(err, &redirect) {
+		redirect.write(w)
+		return
+	}
+
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		setGatewayHeaders(w, md.HeaderMD)
+	}
+
+	err = fromGatewayStatus(err)
+	handler := NewRESTResponseHandler(ctx, nil, err)
+	if _, ok := errordef.Find(err); !ok {
+		if st, ok := status.FromError(err); ok {
+			handler.Map(runtime.HTTPStatusFromCode(st.Code()))
+		}
+	}
+
+	handler.WriteHTTPResponse(ctx, w)
+}
+
+// fromGatewayStatus converts a status into an errordef error. The status
+// message is kept as the description if its code is not registered.
+func fromGatewayStatus(err error) error {
+	if converted := FromStatus(err); converted != err {
+		return converted
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	if known, ok := gatewayErrors[st.Code()]; ok {
+		return xerror.Enrich(known, "%s", st.Message())
+	}
+
+	return err
+}
+
+// gatewayRedirect is returned by GatewayForwardResponse to stop the gateway
+// from writing the message, the redirect is written by GatewayErrorHandler.
+type gatewayRedirect struct {
+	location string
+	code     int
+}
+
+func (r *gatewayRedirect) Error() string {
+	return "redirect to " + r.location
+}
+
+// write responds with the redirect only, the headers forwarded from the
+// metadata and the session cookie are kept.
+func (r *gatewayRedirect) write(w http.ResponseWriter) {
+	w.Header().Del("Content-Type")
+	w.Header().Set("Location", r.location)
+	w.WriteHeader(r.code)
+}
+
+// GatewayForwardResponse saves the session and follows the redirect set by
+// SetGatewayRedirect before the gateway writes a successful response. The
+// redirect is responded without a body, so it needs GatewayErrorHandler as in
+// GatewayServeMuxOptions.
+func GatewayForwardResponse(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	saveSession(ctx, w)
+
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	location := md.HeaderMD.Get(gatewayRedirectLocationKey)
+	if len(location) != 1 {
+		return nil
+	}
+
+	code := http.StatusFound
+	if values := md.HeaderMD.Get(gatewayRedirectCodeKey); len(values) == 1 {
+		if parsed, err := strconv.Atoi(values[0]); err == nil && parsed >= 300 && parsed < 400 {
+			code = parsed
+		}
+	}
+
+	return &gatewayRedirect{location: location[0], code: code}
+}
+
+// SetGatewayRedirect makes the gateway redirect the client to url instead of
+// responding with the message returned by the gRPC method, e.g. at the end of
+// an OAuth2 authorization. It is ignored by gRPC clients.
+func SetGatewayRedirect(ctx context.Context, url string, code int) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(
+		gatewayRedirectLocationKey, url,
+		gatewayRedirectCodeKey, strconv.Itoa(code),
+	))
+}
+
+// GatewayOutgoingHeaderMatcher forwards the rate limit headers as is and the
+// other header metadata with the runtime.MetadataHeaderPrefix. The redirect
+// metadata is not forwarded.
+func GatewayOutgoingHeaderMatcher(key string) (string, bool) {
+	key = strings.ToLower(key)
+
+	switch {
+	case key == gatewayRedirectLocationKey || key == gatewayRedirectCodeKey:
+		return "", false
+	case gatewayHeaders[key]:
+		return textproto.CanonicalMIMEHeaderKey(key), true
+	default:
+		return runtime.MetadataHeaderPrefix + key, true
+	}
+}
+
+// GatewayMetadata forwards the request id of the gateway to the gRPC server,
+// so that both log and respond with the same request id.
+func GatewayMetadata(ctx context.Context, _ *http.Request) metadata.MD {
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		return metadata.Pairs(RequestIDMetadataKey, requestID)
+	}
+
+	return nil
+}
+
+func setGatewayHeaders(w http.ResponseWriter, md metadata.MD) {
+	for key, values := range md {
+		if header, ok := GatewayOutgoingHeaderMatcher(key); ok {
+			for _, value := range values {
+				w.Header().Add(header, value)
+			}
+		}
+	}
+}
+
+// GatewayMarshaler marshals the messages returned by the gRPC server into the
+// data of a successful RESTResponse. Fields are named as in the proto files.
+type GatewayMarshaler struct {
+	runtime.JSONPb
+}
+
+func NewGatewayMarshaler() *GatewayMarshaler {
+	return &GatewayMarshaler{
+		JSONPb: runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		},
+	}
+}
+
+func (m *GatewayMarshaler) Marshal(v any) ([]byte, error) {
+	data, err := m.JSONPb.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(NewRESTResponse(json.RawMessage(data)))
+}
@@ -0,0 +1,127 @@
+package response_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/shared/sharedtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// transportStream captures the header set by a gRPC method.
+type transportStream struct {
+	grpc.ServerTransportStream
+
+	header metadata.MD
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// serveGateway serves a request by a gateway ServeMux, as a generated handler
+// does after the gRPC method returned resp and header, or err.
+func serveGateway(t *testing.T, header metadata.MD, resp proto.Message, err error) *http.Response {
+	mux := runtime.NewServeMux(response.GatewayServeMuxOptions()...)
+
+	handler := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := response.WithRequestID(r.Context(), "request-id")
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{HeaderMD: header})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, resp, mux.GetForwardResponseOptions()...)
+	}
+
+	if err := mux.HandlePath(http.MethodGet, "/callback", handler); err != nil {
+		t.Fatalf("HandlePath() err = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/callback", nil))
+
+	return w.Result()
+}
+
+func TestGatewayForwardResponse(t *testing.T) {
+	resp := serveGateway(t, metadata.Pairs("ratelimit-remaining", "9"), wrapperspb.String("done"), nil)
+
+	var data string
+	sharedtest.AssertRESTSuccess(t, resp, http.StatusOK, &data)
+
+	if data != "done" {
+		t.Errorf("data = %q, want %q", data, "done")
+	}
+
+	if got := resp.Header.Get("RateLimit-Remaining"); got != "9" {
+		t.Errorf("RateLimit-Remaining = %q, want 9", got)
+	}
+}
+
+func TestGatewayRedirect(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		want int
+	}{
+		{name: "see other", code: http.StatusSeeOther, want: http.StatusSeeOther},
+		{name: "invalid code", code: http.StatusOK, want: http.StatusFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &transportStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			if err := response.SetGatewayRedirect(ctx, "https://client.example/callback?code=xyz", tt.code); err != nil {
+				t.Fatalf("SetGatewayRedirect() err = %v", err)
+			}
+
+			header := metadata.Join(stream.header, metadata.Pairs("ratelimit-remaining", "9"))
+			resp := serveGateway(t, header, wrapperspb.String("done"), nil)
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+
+			if got := resp.Header.Get("Location"); got != "https://client.example/callback?code=xyz" {
+				t.Errorf("Location = %q, want the redirect url", got)
+			}
+
+			if got := resp.Header.Get("RateLimit-Remaining"); got != "9" {
+				t.Errorf("RateLimit-Remaining = %q, want the headers to be kept", got)
+			}
+
+			for _, key := range []string{"Content-Type", "Grpc-Metadata-X-Gateway-Redirect-Location"} {
+				if got := resp.Header.Get(key); got != "" {
+					t.Errorf("%s = %q, want none", key, got)
+				}
+			}
+
+			if body, _ := io.ReadAll(resp.Body); len(body) != 0 {
+				t.Errorf("body = %q, want none, the message is not written on a redirect", body)
+			}
+		})
+	}
+}
+
+func TestGatewayErrorHandler(t *testing.T) {
+	resp := serveGateway(t, nil, nil, status.Error(codes.NotFound, "user not found"))
+
+	sharedtest.AssertRESTError(t, resp, http.StatusNotFound, errordef.ErrNotFound)
+}
@@ -3,14 +3,26 @@ package response
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/todennus/shared/errordef"
 	"github.com/todennus/x/xcontext"
 	"github.com/todennus/x/xerror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// ErrorDomain is the domain of the ErrorInfo attached to returned statuses.
+const ErrorDomain = "todennus"
+
+// RetryDelay is the delay suggested to clients by the RetryInfo of retryable
+// errors, e.g. server timeouts.
+var RetryDelay = time.Second
+
 type ResponseHandler[D any] struct {
 	err         error
 	resp        D
@@ -59,8 +71,22 @@ func (h *ResponseHandler[D]) Map(code codes.Code, errs ...error) *ResponseHandle
 	return h
 }
 
+// mapCatalog maps the error to the gRPC code declared in the errordef catalog
+// if no explicit Map matched it.
+func (h *ResponseHandler[D]) mapCatalog() *ResponseHandler[D] {
+	if h.err == nil || h.code != codes.Unknown {
+		return h
+	}
+
+	if definition, ok := errordef.Find(h.err); ok && definition.GRPCCode != codes.OK {
+		h.code = definition.GRPCCode
+	}
+
+	return h
+}
+
 func (h *ResponseHandler[D]) Finalize(ctx context.Context) (D, error) {
-	h.Map(codes.Internal)
+	h.mapCatalog().Map(codes.Internal)
 
 	if h.code == codes.Unknown {
 		h.code = h.defaultCode
@@ -68,6 +94,14 @@ func (h *ResponseHandler[D]) Finalize(ctx context.Context) (D, error) {
 
 	var defaultResp D
 	if h.err != nil {
+		var violations []errordef.FieldViolation
+		var violationErr *errordef.ViolationError
+		if errors.As(h.err, &violationErr) {
+			violations = violationErr.Violations
+		}
+
+		definition, _ := errordef.Find(h.err)
+
 		var richError xerror.RichError
 		if errors.As(h.err, &richError) {
 			if richError.Detail() != nil {
@@ -86,8 +120,120 @@ func (h *ResponseHandler[D]) Finalize(ctx context.Context) (D, error) {
 			h.err = errors.New("unexpected_server_error: an unexpected error occured")
 		}
 
-		return defaultResp, status.Errorf(h.code, h.err.Error())
+		return defaultResp, newStatus(ctx, h.code, h.err, violations, definition.Retryable).Err()
 	}
 
 	return h.resp, nil
 }
+
+// newStatus creates the status of an error. The message keeps the form
+// "<code>:<description>" for clients which do not read the details.
+func newStatus(
+	ctx context.Context,
+	code codes.Code,
+	err error,
+	violations []errordef.FieldViolation,
+	retryable bool,
+) *status.Status {
+	st := status.New(code, err.Error())
+	errorCode, _, _ := strings.Cut(err.Error(), ":")
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: errorCode, Domain: ErrorDomain}}
+
+	if len(violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+
+		details = append(details, badRequest)
+	}
+
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		details = append(details, &errdetails.RequestInfo{RequestId: requestID})
+	}
+
+	if retryable {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(RetryDelay)})
+	}
+
+	withDetails, detailErr := st.WithDetails(details...)
+	if detailErr != nil {
+		xcontext.Logger(ctx).Warn("failed-to-attach-error-details", "err", detailErr)
+		return st
+	}
+
+	return withDetails
+}
+
+// FromStatus converts an error returned by a service into the registered
+// errordef error of its code, so that it can be checked with errors.Is. Field
+// violations are restored as an errordef.ViolationError. The error is
+// returned as is if it is not a status or the code is unknown.
+func FromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+
+	errorCode, description, found := strings.Cut(st.Message(), ":")
+	if !found {
+		errorCode = ""
+	}
+
+	var violations []errordef.FieldViolation
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if detail.GetDomain() == ErrorDomain {
+				errorCode = detail.GetReason()
+			}
+		case *errdetails.BadRequest:
+			for _, violation := range detail.GetFieldViolations() {
+				violations = append(violations, errordef.FieldViolation{
+					Field:       violation.GetField(),
+					Description: violation.GetDescription(),
+				})
+			}
+		}
+	}
+
+	known := errordef.Lookup(errorCode)
+	if known == nil {
+		return err
+	}
+
+	if len(violations) > 0 && errors.Is(known, errordef.ErrRequestInvalid) {
+		return errordef.NewViolationError(violations...)
+	}
+
+	// Errors which are already rich, e.g. errordef.ErrServerTimeout, carry
+	// their own description.
+	var richError xerror.RichError
+	if errors.As(known, &richError) {
+		return known
+	}
+
+	return xerror.Enrich(known, "%s", description)
+}
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// WithRequestID sets the request id of the context, as xcontext.WithRequestID
+// does, and records it for RequestIDFromContext.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = xcontext.WithRequestID(ctx, requestID)
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request id set by WithRequestID. Unlike
+// xcontext.RequestID, it does not panic if the context has none.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok
+}
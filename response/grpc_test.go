@@ -0,0 +1,185 @@
+package response_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xerror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func finalize(ctx context.Context, err error) *status.Status {
+	_, err = response.NewResponseHandler(ctx, any(nil), err).Finalize(ctx)
+	return status.Convert(err)
+}
+
+func TestFinalizeDetails(t *testing.T) {
+	timeoutCtx, cancel := context.WithCancelCause(context.Background())
+	cancel(errordef.ErrServerTimeout)
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		err            error
+		wantCode       codes.Code
+		wantReason     string
+		wantViolations int
+		wantRequestID  string
+		wantRetry      bool
+	}{
+		{
+			name:       "catalog",
+			ctx:        context.Background(),
+			err:        xerror.Enrich(errordef.ErrNotFound, "user is not found"),
+			wantCode:   codes.NotFound,
+			wantReason: "not_found",
+		},
+		{
+			name:           "violations",
+			ctx:            context.Background(),
+			err:            errordef.NewViolationError(errordef.FieldViolation{Field: "name", Description: "is required"}),
+			wantCode:       codes.InvalidArgument,
+			wantReason:     "invalid_request",
+			wantViolations: 1,
+		},
+		{
+			name:          "request id",
+			ctx:           response.WithRequestID(context.Background(), "request-id"),
+			err:           xerror.Enrich(errordef.ErrForbidden, "forbidden"),
+			wantCode:      codes.PermissionDenied,
+			wantReason:    "forbidden",
+			wantRequestID: "request-id",
+		},
+		{
+			name:       "timeout",
+			ctx:        timeoutCtx,
+			err:        errors.New("canceled"),
+			wantCode:   codes.DeadlineExceeded,
+			wantReason: "server_timeout",
+			wantRetry:  true,
+		},
+		{
+			name:       "unexpected error",
+			ctx:        context.Background(),
+			err:        errors.New("database is down"),
+			wantCode:   codes.Internal,
+			wantReason: "unexpected_server_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := finalize(tt.ctx, tt.err)
+			if st.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v", st.Code(), tt.wantCode)
+			}
+
+			var reason, requestID string
+			var violations int
+			var retry bool
+			for _, detail := range st.Details() {
+				switch detail := detail.(type) {
+				case *errdetails.ErrorInfo:
+					if detail.GetDomain() != response.ErrorDomain {
+						t.Errorf("domain = %q, want %q", detail.GetDomain(), response.ErrorDomain)
+					}
+					reason = detail.GetReason()
+				case *errdetails.BadRequest:
+					violations = len(detail.GetFieldViolations())
+				case *errdetails.RequestInfo:
+					requestID = detail.GetRequestId()
+				case *errdetails.RetryInfo:
+					retry = detail.GetRetryDelay().AsDuration() == response.RetryDelay
+				}
+			}
+
+			if reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+
+			if violations != tt.wantViolations {
+				t.Errorf("violations = %d, want %d", violations, tt.wantViolations)
+			}
+
+			if requestID != tt.wantRequestID {
+				t.Errorf("request id = %q, want %q", requestID, tt.wantRequestID)
+			}
+
+			if retry != tt.wantRetry {
+				t.Errorf("retry = %v, want %v", retry, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestFinalizeMap(t *testing.T) {
+	ctx := context.Background()
+	var err error = xerror.Enrich(errordef.ErrNotFound, "user is not found")
+
+	_, err = response.NewResponseHandler(ctx, any(nil), err).
+		Map(codes.PermissionDenied, errordef.ErrNotFound).
+		Finalize(ctx)
+
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("code = %v, want %v, Map must override the catalog", code, codes.PermissionDenied)
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	ctx := context.Background()
+	plain := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "with details", err: finalize(ctx, xerror.Enrich(errordef.ErrNotFound, "user is not found")).Err(), want: errordef.ErrNotFound},
+		{name: "message only", err: status.Error(codes.PermissionDenied, "forbidden: no access"), want: errordef.ErrForbidden},
+		{name: "rich error", err: finalize(ctx, errordef.ErrServerTimeout).Err(), want: errordef.ErrServerTimeout},
+		{name: "unknown code", err: status.Error(codes.Internal, "other_error: failed"), want: nil},
+		{name: "not a status", err: plain, want: plain},
+		{name: "nil", err: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := response.FromStatus(tt.err)
+			if tt.want == nil {
+				if got != tt.err {
+					t.Errorf("FromStatus() = %v, want the error unchanged", got)
+				}
+				return
+			}
+
+			if !errors.Is(got, tt.want) {
+				t.Errorf("FromStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromStatusViolations(t *testing.T) {
+	ctx := context.Background()
+	violation := errordef.FieldViolation{Field: "name", Description: "is required"}
+
+	err := response.FromStatus(finalize(ctx, errordef.NewViolationError(violation)).Err())
+
+	var violationErr *errordef.ViolationError
+	if !errors.As(err, &violationErr) {
+		t.Fatalf("FromStatus() = %v, want a ViolationError", err)
+	}
+
+	if len(violationErr.Violations) != 1 || violationErr.Violations[0] != violation {
+		t.Errorf("Violations = %v, want [%v]", violationErr.Violations, violation)
+	}
+
+	if !errors.Is(err, errordef.ErrRequestInvalid) {
+		t.Errorf("FromStatus() = %v, want %v", err, errordef.ErrRequestInvalid)
+	}
+}
@@ -3,17 +3,31 @@ package response
 import (
 	"context"
 	"net/http"
+	"strings"
 
+	"github.com/todennus/shared/tracing"
+	"github.com/todennus/x/session"
 	"github.com/todennus/x/xcontext"
 	"github.com/todennus/x/xhttp"
 )
 
+// ErrorCodeSetter is implemented by response writers which want to know the
+// error code of the written response, e.g. for logging or metrics.
+type ErrorCodeSetter interface {
+	SetErrorCode(code string)
+}
+
 func WriteError(ctx context.Context, w http.ResponseWriter, code int, err error) {
 	Write(ctx, w, code, NewRESTErrorResponse(ctx, err))
 }
 
 func Write(ctx context.Context, w http.ResponseWriter, code int, resp any) {
-	xcontext.SessionManager(ctx).Save(w, xcontext.Session(ctx))
+	if restResp, ok := resp.(*RESTResponse); ok && restResp.Error != "" {
+		setErrorCode(w, restResp.Error)
+		tracing.SetErrorCode(ctx, restResp.Error)
+	}
+
+	saveSession(ctx, w)
 	if err := xhttp.WriteResponseJSON(w, code, resp); err != nil {
 		xcontext.Logger(ctx).Critical("failed to write response", "err", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -21,6 +35,66 @@ func Write(ctx context.Context, w http.ResponseWriter, code int, resp any) {
 }
 
 func Redirect(ctx context.Context, w http.ResponseWriter, r *http.Request, url string, code int) {
-	xcontext.SessionManager(ctx).Save(w, xcontext.Session(ctx))
+	saveSession(ctx, w)
 	http.Redirect(w, r, url, code)
 }
+
+func setErrorCode(w http.ResponseWriter, code string) {
+	for w != nil {
+		if setter, ok := w.(ErrorCodeSetter); ok {
+			setter.SetErrorCode(code)
+		}
+
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+
+		w = unwrapper.Unwrap()
+	}
+}
+
+// saveSession refreshes the session cookie. Responses written before the
+// session is loaded, e.g. by a rejecting middleware, have no session to save.
+func saveSession(ctx context.Context, w http.ResponseWriter) {
+	s := xcontext.Session(ctx)
+	if s == nil {
+		return
+	}
+
+	// An expired session is cleared by middleware.WithSessionStore. Unless a
+	// new session has been saved since, the cookie clearing it is kept.
+	if s.ID() == "" && hasSessionCookie(w) {
+		return
+	}
+
+	removeSessionCookie(w)
+	xcontext.SessionManager(ctx).Save(w, s)
+}
+
+func isSessionCookie(cookie string) bool {
+	return strings.HasPrefix(cookie, session.SessionIDKey+"=")
+}
+
+func hasSessionCookie(w http.ResponseWriter) bool {
+	for _, cookie := range w.Header().Values("Set-Cookie") {
+		if isSessionCookie(cookie) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeSessionCookie removes the session cookie already set on the response,
+// so that the response sets it only once.
+func removeSessionCookie(w http.ResponseWriter) {
+	header := w.Header()
+	cookies := header.Values("Set-Cookie")
+	header.Del("Set-Cookie")
+	for _, cookie := range cookies {
+		if !isSessionCookie(cookie) {
+			header.Add("Set-Cookie", cookie)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package response
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"time"
@@ -35,11 +36,17 @@ func NewRESTMetadata(ctx context.Context) *RESTMetadata {
 }
 
 type RESTResponse struct {
-	Status           RESTResponseStatus `json:"status,omitempty"`
-	Data             any                `json:"data,omitempty"`
-	Error            string             `json:"error,omitempty"`
-	ErrorDescription string             `json:"error_description,omitempty"`
-	Metadata         *RESTMetadata      `json:"metadata,omitempty"`
+	Status           RESTResponseStatus   `json:"status,omitempty"`
+	Data             any                  `json:"data,omitempty"`
+	Error            string               `json:"error,omitempty"`
+	ErrorDescription string               `json:"error_description,omitempty"`
+	Violations       []RESTFieldViolation `json:"violations,omitempty"`
+	Metadata         *RESTMetadata        `json:"metadata,omitempty"`
+}
+
+type RESTFieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
 }
 
 func NewRESTResponse(data any) *RESTResponse {
@@ -62,7 +69,19 @@ func NewRESTErrorResponse(ctx context.Context, err error) *RESTResponse {
 			}
 		}
 
-		return NewRESTErrorResponseWithMessage(ctx, richError.Code().Error(), richError.Description())
+		response := NewRESTErrorResponseWithMessage(ctx, richError.Code().Error(), richError.Description())
+
+		var violationErr *errordef.ViolationError
+		if errors.As(err, &violationErr) {
+			for _, violation := range violationErr.Violations {
+				response.Violations = append(response.Violations, RESTFieldViolation{
+					Field:       violation.Field,
+					Description: violation.Description,
+				})
+			}
+		}
+
+		return response
 	}
 
 	xcontext.Logger(ctx).Critical("internal-error", "err", err)
@@ -100,8 +119,17 @@ func RESTWriteLogInvalidRequestError(ctx context.Context, w http.ResponseWriter,
 	}
 
 	var code int
+	var maxBytesErr *http.MaxBytesError
+	var richError xerror.RichError
 	response := &RESTResponse{}
 	switch {
+	case errors.As(err, &maxBytesErr):
+		code = http.StatusRequestEntityTooLarge
+		response = NewRESTErrorResponseWithMessage(ctx, errordef.ErrRequestTooLarge.Error(),
+			fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.Is(err, errordef.ErrRequestInvalid) && errors.As(err, &richError):
+		code = http.StatusBadRequest
+		response = NewRESTErrorResponse(ctx, err)
 	case xerror.Is(err, xhttp.ErrHTTPBadRequest, errordef.ErrRequestInvalid):
 		code = http.StatusBadRequest
 		response = NewRESTErrorResponseWithMessage(ctx, "invalid_request", err.Error())
@@ -158,8 +186,22 @@ func (h *RESTResponseHandler) Map(code int, errs ...error) *RESTResponseHandler
 	return h
 }
 
+// mapCatalog maps the error to the HTTP status declared in the errordef
+// catalog if no explicit Map matched it.
+func (h *RESTResponseHandler) mapCatalog() *RESTResponseHandler {
+	if h.err == nil || h.code != -1 {
+		return h
+	}
+
+	if definition, ok := errordef.Find(h.err); ok && definition.HTTPStatus != 0 {
+		h.code = definition.HTTPStatus
+	}
+
+	return h
+}
+
 func (h *RESTResponseHandler) WriteHTTPResponse(ctx context.Context, w http.ResponseWriter) {
-	h.Map(http.StatusInternalServerError)
+	h.mapCatalog().Map(http.StatusInternalServerError)
 
 	if h.code == -1 {
 		h.code = h.defaultCode
@@ -176,7 +218,7 @@ func (h *RESTResponseHandler) WriteHTTPResponse(ctx context.Context, w http.Resp
 }
 
 func (h *RESTResponseHandler) WriteHTTPResponseWithoutWrap(ctx context.Context, w http.ResponseWriter) {
-	h.Map(http.StatusInternalServerError)
+	h.mapCatalog().Map(http.StatusInternalServerError)
 
 	if h.code == -1 {
 		h.code = h.defaultCode
@@ -195,7 +237,7 @@ func (h *RESTResponseHandler) WriteHTTPResponseWithoutWrap(ctx context.Context,
 }
 
 func (h *RESTResponseHandler) Redirect(ctx context.Context, w http.ResponseWriter, r *http.Request, code int) {
-	h.Map(http.StatusInternalServerError)
+	h.mapCatalog().Map(http.StatusInternalServerError)
 
 	if h.code == -1 {
 		h.code = code
@@ -0,0 +1,44 @@
+package response_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/x/xerror"
+)
+
+func TestRESTResponseHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		maps map[int]error
+		want int
+	}{
+		{name: "success", want: http.StatusOK},
+		{name: "catalog", err: xerror.Enrich(errordef.ErrNotFound, "user is not found"), want: http.StatusNotFound},
+		{name: "explicit map", err: xerror.Enrich(errordef.ErrNotFound, "user is not found"), maps: map[int]error{http.StatusForbidden: errordef.ErrNotFound}, want: http.StatusForbidden},
+		{name: "unmatched map", err: xerror.Enrich(errordef.ErrDuplicated, "user exists"), maps: map[int]error{http.StatusForbidden: errordef.ErrNotFound}, want: http.StatusConflict},
+		{name: "unknown", err: errors.New("database is down"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := response.WithRequestID(context.Background(), "request-id")
+			handler := response.NewRESTResponseHandler(ctx, "data", tt.err)
+			for code, err := range tt.maps {
+				handler.Map(code, err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.WriteHTTPResponse(ctx, w)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package sessionstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/todennus/x/session"
+	"github.com/todennus/x/xcrypto"
+)
+
+// Metadata keys stored along with the data of a session.
+const (
+	metaID      = "_sid"
+	metaCreated = "_created"
+	metaActive  = "_active"
+	metaBinding = "_bind"
+)
+
+var _ Backend = (*CookieBackend)(nil)
+
+// CookieBackend stores the whole session in the cookie, signed and encrypted
+// by the first key pair. The other key pairs are only used to decode cookies
+// issued before a key rotation.
+type CookieBackend struct {
+	codecs      []securecookie.Codec
+	lifetime    time.Duration
+	idleTimeout time.Duration
+}
+
+// NewCookieBackend creates a cookie backend. A zero lifetime or idle timeout
+// disables the corresponding expiration. keyPairs are the authentication and
+// encryption keys, from the newest to the oldest.
+func NewCookieBackend(lifetime, idleTimeout time.Duration, keyPairs ...[]byte) *CookieBackend {
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		// The expiration is checked against the metadata instead.
+		codec.(*securecookie.SecureCookie).MaxAge(0)
+	}
+
+	return &CookieBackend{
+		codecs:      codecs,
+		lifetime:    lifetime,
+		idleTimeout: idleTimeout,
+	}
+}
+
+func (b *CookieBackend) Load(ctx context.Context, s *session.Session) (map[any]any, error) {
+	if s == nil || s.ID() == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	data := map[any]any{}
+	if err := securecookie.DecodeMulti(session.SessionIDKey, s.ID(), &data, b.codecs...); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionInvalid, err.Error())
+	}
+
+	if err := checkExpiration(data, b.lifetime, b.idleTimeout); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (b *CookieBackend) Save(ctx context.Context, s *session.Session, data map[any]any) error {
+	now := time.Now().Unix()
+	data[metaID] = xcrypto.RandString(32)
+	data[metaCreated] = now
+	data[metaBinding] = newBinding()
+
+	if previous, err := b.Load(ctx, s); err == nil {
+		data[metaID] = previous[metaID]
+		data[metaCreated] = previous[metaCreated]
+		if binding := Binding(previous); binding != "" {
+			data[metaBinding] = binding
+		}
+	}
+
+	data[metaActive] = now
+	return b.encode(s, data)
+}
+
+func (b *CookieBackend) Renew(ctx context.Context, s *session.Session) error {
+	data, err := b.Load(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if !shouldRenew(data, b.idleTimeout) {
+		return nil
+	}
+
+	data[metaActive] = time.Now().Unix()
+	return b.encode(s, data)
+}
+
+func (b *CookieBackend) Regenerate(ctx context.Context, s *session.Session) error {
+	data, err := b.Load(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// The creation time is kept, regenerating does not extend the lifetime.
+	data[metaID] = xcrypto.RandString(32)
+	data[metaActive] = time.Now().Unix()
+	data[metaBinding] = newBinding()
+	return b.encode(s, data)
+}
+
+func (b *CookieBackend) encode(s *session.Session, data map[any]any) error {
+	encoded, err := securecookie.EncodeMulti(session.SessionIDKey, data, b.codecs...)
+	if err != nil {
+		return err
+	}
+
+	s.SetID(encoded)
+	return nil
+}
+
+func checkExpiration(data map[any]any, lifetime, idleTimeout time.Duration) error {
+	now := time.Now()
+
+	created, ok := data[metaCreated].(int64)
+	if !ok {
+		return fmt.Errorf("%w: missing creation time", ErrSessionInvalid)
+	}
+
+	if lifetime > 0 && now.Sub(time.Unix(created, 0)) > lifetime {
+		return ErrSessionExpired
+	}
+
+	active, ok := data[metaActive].(int64)
+	if !ok {
+		return fmt.Errorf("%w: missing activity time", ErrSessionInvalid)
+	}
+
+	if idleTimeout > 0 && now.Sub(time.Unix(active, 0)) > idleTimeout {
+		return ErrSessionExpired
+	}
+
+	return nil
+}
+
+// shouldRenew limits the renewal of the idle timeout to once per tenth of the
+// timeout, so that the session is not rewritten on every request.
+func shouldRenew(data map[any]any, idleTimeout time.Duration) bool {
+	if idleTimeout <= 0 {
+		return false
+	}
+
+	active, _ := data[metaActive].(int64)
+	return time.Since(time.Unix(active, 0)) >= idleTimeout/10
+}
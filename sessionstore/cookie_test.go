@@ -0,0 +1,218 @@
+package sessionstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/todennus/shared/sessionstore"
+	"github.com/todennus/x/session"
+	"github.com/xybor-x/snowflake"
+)
+
+type testSession struct {
+	UserID snowflake.ID `session:"user_id"`
+	Name   string       `session:"name"`
+}
+
+func (s testSession) SessionUserID() snowflake.ID {
+	return s.UserID
+}
+
+func newKeyPair() [][]byte {
+	return [][]byte{securecookie.GenerateRandomKey(32), securecookie.GenerateRandomKey(32)}
+}
+
+func TestCookieBackendRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := sessionstore.NewStore[testSession](sessionstore.NewCookieBackend(time.Hour, time.Hour, newKeyPair()...))
+
+	s := &session.Session{}
+	if err := store.Save(ctx, s, &testSession{UserID: 42, Name: "alice"}); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	loaded, err := store.Load(ctx, s)
+	if err != nil {
+		t.Fatalf("Load() err = %v", err)
+	}
+
+	if loaded.UserID != 42 || loaded.Name != "alice" {
+		t.Errorf("Load() = %+v, want {42 alice}", *loaded)
+	}
+}
+
+func TestCookieBackendRejects(t *testing.T) {
+	ctx := context.Background()
+	keys := newKeyPair()
+	backend := sessionstore.NewCookieBackend(time.Hour, time.Hour, keys...)
+	store := sessionstore.NewStore[testSession](backend)
+
+	s := &session.Session{}
+	if err := store.Save(ctx, s, &testSession{Name: "alice"}); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	other := &session.Session{}
+	otherStore := sessionstore.NewStore[testSession](sessionstore.NewCookieBackend(time.Hour, time.Hour, newKeyPair()...))
+	if err := otherStore.Save(ctx, other, &testSession{Name: "mallory"}); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	tampered := []byte(s.ID())
+	tampered[len(tampered)/2] ^= 1
+
+	tests := []struct {
+		name string
+		id   string
+		want error
+	}{
+		{name: "empty", id: "", want: sessionstore.ErrSessionNotFound},
+		{name: "tampered", id: string(tampered), want: sessionstore.ErrSessionInvalid},
+		{name: "other key", id: other.ID(), want: sessionstore.ErrSessionInvalid},
+		{name: "garbage", id: "not-a-cookie", want: sessionstore.ErrSessionInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &session.Session{}
+			s.SetID(tt.id)
+
+			if _, err := backend.Load(ctx, s); !errors.Is(err, tt.want) {
+				t.Errorf("Load() err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieBackendKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKeys := newKeyPair()
+	oldStore := sessionstore.NewStore[testSession](sessionstore.NewCookieBackend(time.Hour, time.Hour, oldKeys...))
+
+	s := &session.Session{}
+	if err := oldStore.Save(ctx, s, &testSession{Name: "alice"}); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	keys := append(newKeyPair(), oldKeys...)
+	store := sessionstore.NewStore[testSession](sessionstore.NewCookieBackend(time.Hour, time.Hour, keys...))
+
+	loaded, err := store.Load(ctx, s)
+	if err != nil {
+		t.Fatalf("Load() err = %v", err)
+	}
+
+	if loaded.Name != "alice" {
+		t.Errorf("Name = %q, want %q", loaded.Name, "alice")
+	}
+
+	// The session is re-encoded by the newest key when it is saved again.
+	if err := store.Save(ctx, s, loaded); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	if _, err := sessionstore.NewCookieBackend(time.Hour, time.Hour, keys[:2]...).Load(ctx, s); err != nil {
+		t.Errorf("Load() by the newest key err = %v", err)
+	}
+}
+
+func TestCookieBackendIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := sessionstore.NewCookieBackend(time.Hour, time.Second, newKeyPair()...)
+	store := sessionstore.NewStore[testSession](backend)
+
+	s := &session.Session{}
+	if err := store.Save(ctx, s, &testSession{Name: "alice"}); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	time.Sleep(2100 * time.Millisecond)
+
+	if _, err := backend.Load(ctx, s); !errors.Is(err, sessionstore.ErrSessionExpired) {
+		t.Errorf("Load() err = %v, want %v", err, sessionstore.ErrSessionExpired)
+	}
+
+	loaded, err := store.Load(ctx, s)
+	if err != nil {
+		t.Fatalf("Store.Load() err = %v", err)
+	}
+
+	if loaded.Name != "" {
+		t.Errorf("Name = %q, want empty", loaded.Name)
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	t.Parallel()
+
+	backends := map[string]func(lifetime time.Duration) sessionstore.Backend{
+		"cookie": func(lifetime time.Duration) sessionstore.Backend {
+			return sessionstore.NewCookieBackend(lifetime, 0, newKeyPair()...)
+		},
+		"server": func(lifetime time.Duration) sessionstore.Backend {
+			return sessionstore.NewMemoryBackend(lifetime, 0)
+		},
+	}
+
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			backend := newBackend(3 * time.Second)
+			store := sessionstore.NewStore[testSession](backend)
+
+			s := &session.Session{}
+			if err := store.Save(ctx, s, &testSession{UserID: 42, Name: "alice"}); err != nil {
+				t.Fatalf("Save() err = %v", err)
+			}
+
+			data, err := backend.Load(ctx, s)
+			if err != nil {
+				t.Fatalf("Load() err = %v", err)
+			}
+
+			id, binding := s.ID(), sessionstore.Binding(data)
+
+			time.Sleep(1100 * time.Millisecond)
+
+			if err := store.Regenerate(ctx, s); err != nil {
+				t.Fatalf("Regenerate() err = %v", err)
+			}
+
+			if s.ID() == id {
+				t.Errorf("ID was not changed")
+			}
+
+			data, err = backend.Load(ctx, s)
+			if err != nil {
+				t.Fatalf("Load() err = %v", err)
+			}
+
+			if sessionstore.Binding(data) == binding {
+				t.Errorf("Binding was not changed")
+			}
+
+			loaded, err := store.Load(ctx, s)
+			if err != nil {
+				t.Fatalf("Store.Load() err = %v", err)
+			}
+
+			if loaded.UserID != 42 || loaded.Name != "alice" {
+				t.Errorf("Load() = %+v, want {42 alice}", *loaded)
+			}
+
+			// The lifetime still counts from the first save.
+			time.Sleep(2 * time.Second)
+
+			if _, err := backend.Load(ctx, s); !errors.Is(err, sessionstore.ErrSessionExpired) {
+				t.Errorf("Load() err = %v, want %v", err, sessionstore.ErrSessionExpired)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package sessionstore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var _ kv = (*memoryKV)(nil)
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+type memoryKV struct {
+	mu       sync.Mutex
+	sessions map[string]memoryEntry
+	users    map[int64]map[string]struct{}
+}
+
+// NewMemoryBackend stores sessions in the process memory. It is intended for
+// tests and single-instance deployments.
+func NewMemoryBackend(lifetime, idleTimeout time.Duration) *ServerBackend {
+	return &ServerBackend{
+		kv: &memoryKV{
+			sessions: make(map[string]memoryEntry),
+			users:    make(map[int64]map[string]struct{}),
+		},
+		lifetime:    lifetime,
+		idleTimeout: idleTimeout,
+	}
+}
+
+func (kv *memoryKV) get(ctx context.Context, id string) ([]byte, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	entry, ok := kv.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(kv.sessions, id)
+		return nil, nil
+	}
+
+	return entry.value, nil
+}
+
+func (kv *memoryKV) set(ctx context.Context, id string, value []byte, ttl time.Duration) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	kv.sessions[id] = entry
+	return nil
+}
+
+func (kv *memoryKV) del(ctx context.Context, ids ...string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	for _, id := range ids {
+		delete(kv.sessions, id)
+	}
+
+	return nil
+}
+
+func (kv *memoryKV) addUserSession(ctx context.Context, userID int64, id string, ttl time.Duration) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if _, ok := kv.users[userID]; !ok {
+		kv.users[userID] = make(map[string]struct{})
+	}
+
+	kv.users[userID][id] = struct{}{}
+	return nil
+}
+
+func (kv *memoryKV) removeUserSessions(ctx context.Context, userID int64, ids ...string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	for _, id := range ids {
+		delete(kv.users[userID], id)
+	}
+
+	if len(kv.users[userID]) == 0 {
+		delete(kv.users, userID)
+	}
+
+	return nil
+}
+
+func (kv *memoryKV) userSessions(ctx context.Context, userID int64) ([]string, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	ids := make([]string, 0, len(kv.users[userID]))
+	for id := range kv.users[userID] {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
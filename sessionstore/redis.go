@@ -0,0 +1,84 @@
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ kv = (*redisKV)(nil)
+
+type redisKV struct {
+	client *redis.Client
+}
+
+// NewRedisBackend stores sessions in Redis. A zero lifetime or idle timeout
+// disables the corresponding expiration.
+func NewRedisBackend(client *redis.Client, lifetime, idleTimeout time.Duration) *ServerBackend {
+	return &ServerBackend{
+		kv:          &redisKV{client: client},
+		lifetime:    lifetime,
+		idleTimeout: idleTimeout,
+	}
+}
+
+func sessionKey(id string) string {
+	return fmt.Sprintf("session:%s", id)
+}
+
+func userSessionsKey(userID int64) string {
+	return fmt.Sprintf("session:user:%d", userID)
+}
+
+func (kv *redisKV) get(ctx context.Context, id string) ([]byte, error) {
+	value, err := kv.client.Get(ctx, sessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+func (kv *redisKV) set(ctx context.Context, id string, value []byte, ttl time.Duration) error {
+	return kv.client.Set(ctx, sessionKey(id), value, ttl).Err()
+}
+
+func (kv *redisKV) del(ctx context.Context, ids ...string) error {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+
+	return kv.client.Del(ctx, keys...).Err()
+}
+
+func (kv *redisKV) addUserSession(ctx context.Context, userID int64, id string, ttl time.Duration) error {
+	pipe := kv.client.TxPipeline()
+	pipe.SAdd(ctx, userSessionsKey(userID), id)
+	if ttl > 0 {
+		pipe.Expire(ctx, userSessionsKey(userID), ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (kv *redisKV) removeUserSessions(ctx context.Context, userID int64, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	members := make([]any, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, id)
+	}
+
+	return kv.client.SRem(ctx, userSessionsKey(userID), members...).Err()
+}
+
+func (kv *redisKV) userSessions(ctx context.Context, userID int64) ([]string, error) {
+	return kv.client.SMembers(ctx, userSessionsKey(userID)).Result()
+}
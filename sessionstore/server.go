@@ -0,0 +1,238 @@
+package sessionstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/todennus/x/session"
+	"github.com/todennus/x/xcrypto"
+	"github.com/xybor-x/snowflake"
+)
+
+// metaUser is the id of the user owning a session, indexed by ServerBackend
+// to list and revoke the sessions of a user.
+const metaUser = "_uid"
+
+// kv is the storage used by ServerBackend.
+type kv interface {
+	// get returns nil if the key does not exist.
+	get(ctx context.Context, id string) ([]byte, error)
+	set(ctx context.Context, id string, value []byte, ttl time.Duration) error
+	del(ctx context.Context, ids ...string) error
+
+	addUserSession(ctx context.Context, userID int64, id string, ttl time.Duration) error
+	removeUserSessions(ctx context.Context, userID int64, ids ...string) error
+	userSessions(ctx context.Context, userID int64) ([]string, error)
+}
+
+var _ Backend = (*ServerBackend)(nil)
+var _ Revoker = (*ServerBackend)(nil)
+
+// ServerBackend stores sessions on the server side, the cookie only holds an
+// opaque id. Unlike CookieBackend, sessions can be revoked.
+type ServerBackend struct {
+	kv          kv
+	lifetime    time.Duration
+	idleTimeout time.Duration
+}
+
+func (b *ServerBackend) Load(ctx context.Context, s *session.Session) (map[any]any, error) {
+	if s == nil || s.ID() == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	value, err := b.kv.get(ctx, s.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	// The session has expired or has been revoked.
+	if value == nil {
+		return nil, ErrSessionExpired
+	}
+
+	data := map[any]any{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionInvalid, err.Error())
+	}
+
+	if err := checkExpiration(data, b.lifetime, b.idleTimeout); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (b *ServerBackend) Save(ctx context.Context, s *session.Session, data map[any]any) error {
+	now := time.Now().Unix()
+	id := xcrypto.RandString(32)
+	data[metaCreated] = now
+	data[metaBinding] = newBinding()
+
+	if previous, err := b.Load(ctx, s); err == nil {
+		id = s.ID()
+		data[metaCreated] = previous[metaCreated]
+		if binding := Binding(previous); binding != "" {
+			data[metaBinding] = binding
+		}
+	}
+
+	data[metaActive] = now
+	if err := b.write(ctx, id, data); err != nil {
+		return err
+	}
+
+	s.SetID(id)
+	return nil
+}
+
+func (b *ServerBackend) Renew(ctx context.Context, s *session.Session) error {
+	data, err := b.Load(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if !shouldRenew(data, b.idleTimeout) {
+		return nil
+	}
+
+	data[metaActive] = time.Now().Unix()
+	return b.write(ctx, s.ID(), data)
+}
+
+func (b *ServerBackend) Regenerate(ctx context.Context, s *session.Session) error {
+	data, err := b.Load(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// The creation time is kept, regenerating does not extend the lifetime.
+	data[metaActive] = time.Now().Unix()
+	data[metaBinding] = newBinding()
+
+	id := xcrypto.RandString(32)
+	if err := b.write(ctx, id, data); err != nil {
+		return err
+	}
+
+	if err := b.Revoke(ctx, s); err != nil {
+		return err
+	}
+
+	s.SetID(id)
+	return nil
+}
+
+func (b *ServerBackend) Revoke(ctx context.Context, s *session.Session) error {
+	if s == nil || s.ID() == "" {
+		return nil
+	}
+
+	if data, err := b.Load(ctx, s); err == nil {
+		if userID, ok := data[metaUser].(int64); ok {
+			if err := b.kv.removeUserSessions(ctx, userID, s.ID()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := b.kv.del(ctx, s.ID()); err != nil {
+		return err
+	}
+
+	s.SetID("")
+	return nil
+}
+
+func (b *ServerBackend) RevokeUser(ctx context.Context, userID snowflake.ID) error {
+	ids, err := b.kv.userSessions(ctx, userID.Int64())
+	if err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		if err := b.kv.del(ctx, ids...); err != nil {
+			return err
+		}
+	}
+
+	return b.kv.removeUserSessions(ctx, userID.Int64(), ids...)
+}
+
+// ListUser returns the active sessions of a user. The ids of the sessions
+// which have expired are removed from the index of the user.
+func (b *ServerBackend) ListUser(ctx context.Context, userID snowflake.ID) ([]*session.Session, error) {
+	ids, err := b.kv.userSessions(ctx, userID.Int64())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*session.Session, 0, len(ids))
+	expired := []string{}
+	for _, id := range ids {
+		s := &session.Session{}
+		s.SetID(id)
+
+		if _, err := b.Load(ctx, s); err != nil {
+			if errors.Is(err, ErrSessionExpired) {
+				expired = append(expired, id)
+				continue
+			}
+
+			return nil, err
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	if err := b.kv.removeUserSessions(ctx, userID.Int64(), expired...); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (b *ServerBackend) write(ctx context.Context, id string, data map[any]any) error {
+	buffer := bytes.Buffer{}
+	if err := gob.NewEncoder(&buffer).Encode(data); err != nil {
+		return err
+	}
+
+	ttl := b.ttl(data)
+	if err := b.kv.set(ctx, id, buffer.Bytes(), ttl); err != nil {
+		return err
+	}
+
+	if userID, ok := data[metaUser].(int64); ok {
+		if err := b.kv.addUserSession(ctx, userID, id, b.lifetime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ttl returns the time until the session expires, by either the absolute
+// lifetime or the idle timeout.
+func (b *ServerBackend) ttl(data map[any]any) time.Duration {
+	created, _ := data[metaCreated].(int64)
+	active, _ := data[metaActive].(int64)
+
+	var ttl time.Duration
+	if b.lifetime > 0 {
+		ttl = time.Until(time.Unix(created, 0).Add(b.lifetime))
+	}
+
+	if b.idleTimeout > 0 {
+		idle := time.Until(time.Unix(active, 0).Add(b.idleTimeout))
+		if ttl == 0 || idle < ttl {
+			ttl = idle
+		}
+	}
+
+	return ttl
+}
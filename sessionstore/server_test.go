@@ -0,0 +1,152 @@
+package sessionstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/sessionstore"
+	"github.com/todennus/x/session"
+	"github.com/xybor-x/snowflake"
+)
+
+func TestServerBackendSave(t *testing.T) {
+	ctx := context.Background()
+	store := sessionstore.NewStore[testSession](sessionstore.NewMemoryBackend(time.Hour, time.Hour))
+
+	s := &session.Session{}
+	if err := store.Save(ctx, s, &testSession{UserID: 42, Name: "alice"}); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	id := s.ID()
+	if err := store.Save(ctx, s, &testSession{UserID: 42, Name: "bob"}); err != nil {
+		t.Fatalf("Save() err = %v", err)
+	}
+
+	if s.ID() != id {
+		t.Errorf("ID = %q, want %q, saving an existing session must keep its id", s.ID(), id)
+	}
+
+	loaded, err := store.Load(ctx, s)
+	if err != nil {
+		t.Fatalf("Load() err = %v", err)
+	}
+
+	if loaded.Name != "bob" {
+		t.Errorf("Name = %q, want %q", loaded.Name, "bob")
+	}
+}
+
+func TestServerBackendRevoke(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		revoke func(store *sessionstore.Store[testSession], sessions []*session.Session) error
+		want   []bool
+	}{
+		{
+			name: "one session",
+			revoke: func(store *sessionstore.Store[testSession], sessions []*session.Session) error {
+				revoked := *sessions[0]
+				return store.Revoke(ctx, &revoked)
+			},
+			want: []bool{false, true, true},
+		},
+		{
+			name: "user",
+			revoke: func(store *sessionstore.Store[testSession], sessions []*session.Session) error {
+				return store.RevokeUser(ctx, 42)
+			},
+			want: []bool{false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := sessionstore.NewMemoryBackend(time.Hour, time.Hour)
+			store := sessionstore.NewStore[testSession](backend)
+
+			// Two sessions of the user 42 and one of the user 43.
+			sessions := []*session.Session{{}, {}, {}}
+			for i, userID := range []int64{42, 42, 43} {
+				if err := store.Save(ctx, sessions[i], &testSession{UserID: snowflake.ID(userID)}); err != nil {
+					t.Fatalf("Save() err = %v", err)
+				}
+			}
+
+			if err := tt.revoke(store, sessions); err != nil {
+				t.Fatalf("revoke err = %v", err)
+			}
+
+			for i, s := range sessions {
+				_, err := backend.Load(ctx, s)
+				if active := err == nil; active != tt.want[i] {
+					t.Errorf("session %d active = %v, want %v (err = %v)", i, active, tt.want[i], err)
+				}
+
+				if err != nil && !errors.Is(err, sessionstore.ErrSessionExpired) {
+					t.Errorf("session %d err = %v, want %v", i, err, sessionstore.ErrSessionExpired)
+				}
+			}
+
+			listed, err := store.ListUser(ctx, 42)
+			if err != nil {
+				t.Fatalf("ListUser() err = %v", err)
+			}
+
+			active := 0
+			for i := range 2 {
+				if tt.want[i] {
+					active++
+				}
+			}
+
+			if len(listed) != active {
+				t.Errorf("len(ListUser()) = %d, want %d", len(listed), active)
+			}
+		})
+	}
+}
+
+func TestServerBackendIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := sessionstore.NewMemoryBackend(time.Hour, 3*time.Second)
+	store := sessionstore.NewStore[testSession](backend)
+
+	active, idle := &session.Session{}, &session.Session{}
+	for _, s := range []*session.Session{active, idle} {
+		if err := store.Save(ctx, s, &testSession{UserID: 42}); err != nil {
+			t.Fatalf("Save() err = %v", err)
+		}
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+
+	if err := backend.Renew(ctx, active); err != nil {
+		t.Fatalf("Renew() err = %v", err)
+	}
+
+	time.Sleep(1800 * time.Millisecond)
+
+	if _, err := backend.Load(ctx, active); err != nil {
+		t.Errorf("Load() of the renewed session err = %v", err)
+	}
+
+	if _, err := backend.Load(ctx, idle); !errors.Is(err, sessionstore.ErrSessionExpired) {
+		t.Errorf("Load() of the idle session err = %v, want %v", err, sessionstore.ErrSessionExpired)
+	}
+
+	listed, err := store.ListUser(ctx, 42)
+	if err != nil {
+		t.Fatalf("ListUser() err = %v", err)
+	}
+
+	if len(listed) != 1 || listed[0].ID() != active.ID() {
+		t.Errorf("ListUser() = %v, want only the renewed session", listed)
+	}
+}
@@ -0,0 +1,198 @@
+package sessionstore
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/todennus/x/session"
+	"github.com/todennus/x/xcrypto"
+	"github.com/todennus/x/xreflect"
+	"github.com/xybor-x/snowflake"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+	ErrSessionInvalid  = errors.New("session invalid")
+
+	ErrRevokeNotSupported = errors.New("the session backend does not support revocation")
+)
+
+// Backend persists the data of sessions. The data is a map of session tag to
+// field value, as produced by xreflect.ToMap.
+type Backend interface {
+	// Load returns the data of a session. It returns ErrSessionNotFound if the
+	// session has no id, ErrSessionExpired if the idle timeout or the absolute
+	// lifetime is exceeded, and ErrSessionInvalid if the session cannot be
+	// verified, e.g. a tampered cookie.
+	Load(ctx context.Context, s *session.Session) (map[any]any, error)
+
+	// Save stores the data of a session and updates the session id if needed.
+	Save(ctx context.Context, s *session.Session, data map[any]any) error
+
+	// Renew extends the idle timeout of a valid session.
+	Renew(ctx context.Context, s *session.Session) error
+
+	// Regenerate gives a new id to a session and keeps its data. It must be
+	// called when the privilege of the session changes, e.g. after login, to
+	// prevent session fixation.
+	Regenerate(ctx context.Context, s *session.Session) error
+}
+
+// Revoker is implemented by backends which can invalidate sessions before
+// their expiration.
+type Revoker interface {
+	// Revoke invalidates a session and clears its id.
+	Revoke(ctx context.Context, s *session.Session) error
+
+	// RevokeUser invalidates every session of a user.
+	RevokeUser(ctx context.Context, userID snowflake.ID) error
+
+	// ListUser returns the active sessions of a user.
+	ListUser(ctx context.Context, userID snowflake.ID) ([]*session.Session, error)
+}
+
+// UserSession is implemented by session data belonging to a user. It allows
+// backends to list and revoke every session of the user.
+type UserSession interface {
+	SessionUserID() snowflake.ID
+}
+
+// Binding returns a random value of the session data loaded by a Backend.
+// Unlike the session id, which the cookie backend changes on every write, it
+// only changes when the session is regenerated, so that it can bind other
+// secrets to the session, e.g. CSRF tokens. It is empty for sessions saved
+// before it was introduced, until they are saved again.
+func Binding(data map[any]any) string {
+	binding, _ := data[metaBinding].(string)
+	return binding
+}
+
+func newBinding() string {
+	return xcrypto.RandString(32)
+}
+
+// registerTypes registers the type of every session field of a struct, gob
+// cannot decode a value stored as any whose type is not registered, e.g.
+// snowflake.ID.
+func registerTypes(t reflect.Type) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tag, _, _ := strings.Cut(field.Tag.Get("session"), ","); tag == "" {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Interface {
+			continue
+		}
+
+		gob.Register(reflect.Zero(field.Type).Interface())
+	}
+}
+
+// basicValue converts a value of a named type to its underlying basic type,
+// e.g. snowflake.ID to int64, which xreflect.Parse can assign.
+func basicValue(value any) any {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	default:
+		return value
+	}
+}
+
+var _ session.Store[int] = (*Store[int])(nil)
+
+// Store adapts a Backend to the typed session.Store of a service.
+type Store[T any] struct {
+	backend Backend
+}
+
+// NewStore registers the types of the session fields of T to gob, which both
+// the cookie and the server backends use to encode the data of sessions.
+func NewStore[T any](backend Backend) *Store[T] {
+	registerTypes(reflect.TypeFor[T]())
+	return &Store[T]{backend: backend}
+}
+
+// Load returns the zero value of T if the session does not exist or has
+// expired.
+func (store *Store[T]) Load(ctx context.Context, s *session.Session) (*T, error) {
+	result := new(T)
+
+	data, err := store.backend.Load(ctx, s)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrSessionExpired) {
+			return result, nil
+		}
+
+		return nil, err
+	}
+
+	if err := xreflect.Parse(result, false, "session", func(field string) any { return basicValue(data[field]) }); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (store *Store[T]) Save(ctx context.Context, s *session.Session, t *T) error {
+	data := xreflect.ToMap(t, "session")
+	if userSession, ok := any(t).(UserSession); ok && userSession.SessionUserID() != 0 {
+		data[metaUser] = userSession.SessionUserID().Int64()
+	}
+
+	return store.backend.Save(ctx, s, data)
+}
+
+func (store *Store[T]) Regenerate(ctx context.Context, s *session.Session) error {
+	return store.backend.Regenerate(ctx, s)
+}
+
+// Revoke invalidates a session. If the backend does not support revocation,
+// only the id of the session is cleared, the cookie is then replaced by an
+// empty one when the response is written.
+func (store *Store[T]) Revoke(ctx context.Context, s *session.Session) error {
+	if revoker, ok := store.backend.(Revoker); ok {
+		return revoker.Revoke(ctx, s)
+	}
+
+	s.SetID("")
+	return nil
+}
+
+func (store *Store[T]) RevokeUser(ctx context.Context, userID snowflake.ID) error {
+	if revoker, ok := store.backend.(Revoker); ok {
+		return revoker.RevokeUser(ctx, userID)
+	}
+
+	return ErrRevokeNotSupported
+}
+
+// ListUser returns the active sessions of a user, e.g. to show them to the
+// user before revoking one of them.
+func (store *Store[T]) ListUser(ctx context.Context, userID snowflake.ID) ([]*session.Session, error) {
+	if revoker, ok := store.backend.(Revoker); ok {
+		return revoker.ListUser(ctx, userID)
+	}
+
+	return nil, ErrRevokeNotSupported
+}
@@ -0,0 +1,94 @@
+package sharedtest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/response"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// restResponse is a response.RESTResponse whose data is decoded later.
+type restResponse struct {
+	response.RESTResponse
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+func decodeREST(t testing.TB, resp *http.Response) *restResponse {
+	t.Helper()
+
+	body := &restResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		t.Fatalf("failed to decode rest response: %v", err)
+	}
+
+	return body
+}
+
+// AssertRESTSuccess checks the status code of a successful REST response and
+// decodes its data into data, if data is not nil.
+func AssertRESTSuccess(t testing.TB, resp *http.Response, code int, data any) *response.RESTResponse {
+	t.Helper()
+
+	body := decodeREST(t, resp)
+	if resp.StatusCode != code {
+		t.Fatalf("expected status %d, got %d (%s: %s)", code, resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	if body.Status != response.RESTResponseStatusSuccess {
+		t.Fatalf("expected status %q, got %q", response.RESTResponseStatusSuccess, body.Status)
+	}
+
+	if data != nil {
+		if err := json.Unmarshal(body.Data, data); err != nil {
+			t.Fatalf("failed to decode rest data: %v", err)
+		}
+	}
+
+	return &body.RESTResponse
+}
+
+// AssertRESTError checks the status code and, if err is not nil, the error
+// code of a failed REST response, e.g. AssertRESTError(t, resp, 404,
+// errordef.ErrNotFound).
+func AssertRESTError(t testing.TB, resp *http.Response, code int, err error) *response.RESTResponse {
+	t.Helper()
+
+	body := decodeREST(t, resp)
+	if resp.StatusCode != code {
+		t.Fatalf("expected status %d, got %d (%s: %s)", code, resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	if body.Status != response.RESTResponseStatusError {
+		t.Fatalf("expected status %q, got %q", response.RESTResponseStatusError, body.Status)
+	}
+
+	if want := errordef.Code(err); err != nil && body.Error != want {
+		t.Fatalf("expected error %q, got %q (%s)", want, body.Error, body.ErrorDescription)
+	}
+
+	return &body.RESTResponse
+}
+
+// AssertGRPCStatus checks the code of an error returned by a gRPC client and,
+// if want is not nil, that it is the errordef error want.
+func AssertGRPCStatus(t testing.TB, err error, code codes.Code, want error) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("expected a grpc status, got %v", err)
+	}
+
+	if st.Code() != code {
+		t.Fatalf("expected code %s, got %s (%s)", code, st.Code(), st.Message())
+	}
+
+	if want != nil && !errors.Is(response.FromStatus(err), want) {
+		t.Fatalf("expected error %q, got %q", errordef.Code(want), st.Message())
+	}
+}
@@ -0,0 +1,102 @@
+// Package sharedtest helps to test services built on this module without
+// environment variables nor external infrastructure.
+package sharedtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"sync"
+	"testing"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/x/logging"
+	"github.com/todennus/x/xcrypto"
+)
+
+var (
+	rsaKeyOnce    sync.Once
+	rsaPrivateKey string
+	rsaPublicKey  string
+)
+
+// RSAKeys returns a PEM-encoded RSA key pair. It is generated once per test
+// binary because generating a key is slow.
+func RSAKeys() (privateKey, publicKey string) {
+	rsaKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+
+		publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			panic(err)
+		}
+
+		rsaPrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+		rsaPublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	})
+
+	return rsaPrivateKey, rsaPublicKey
+}
+
+// ConfigBuilder builds a config.Config with the default variables, generated
+// token and session keys, and an in-process session store.
+type ConfigBuilder struct {
+	variable config.Variable
+	secret   config.Secret
+}
+
+func NewConfigBuilder() *ConfigBuilder {
+	privateKey, publicKey := RSAKeys()
+
+	variable := config.DefaultVariable()
+	variable.Server.LogLevel = int(logging.LevelWarn)
+	variable.Authentication.TokenIssuer = "sharedtest"
+	variable.Session.Store = config.SessionStoreCookie
+
+	return &ConfigBuilder{
+		variable: variable,
+		secret: config.Secret{
+			Authentication: config.AuthenticationSecret{
+				TokenRSAPrivateKey: privateKey,
+				TokenRSAPublicKey:  publicKey,
+			},
+			Session: config.SessionSecret{
+				AuthenticationKey: xcrypto.RandString(32),
+				EncryptionKey:     xcrypto.RandString(32),
+			},
+		},
+	}
+}
+
+// WithVariable modifies the variables of the config.
+func (b *ConfigBuilder) WithVariable(modify func(variable *config.Variable)) *ConfigBuilder {
+	modify(&b.variable)
+	return b
+}
+
+// WithSecret modifies the secrets of the config.
+func (b *ConfigBuilder) WithSecret(modify func(secret *config.Secret)) *ConfigBuilder {
+	modify(&b.secret)
+	return b
+}
+
+func (b *ConfigBuilder) Build(t testing.TB) *config.Config {
+	t.Helper()
+
+	c, err := config.New(b.variable, b.secret)
+	if err != nil {
+		t.Fatalf("failed to build config: %v", err)
+	}
+
+	return c
+}
+
+// NewConfig builds a config with the defaults of NewConfigBuilder.
+func NewConfig(t testing.TB) *config.Config {
+	t.Helper()
+	return NewConfigBuilder().Build(t)
+}
@@ -0,0 +1,83 @@
+package sharedtest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// GRPC is a gRPC server and a client connected to it in memory.
+type GRPC struct {
+	Server *grpc.Server
+	Conn   *grpc.ClientConn
+}
+
+// NewGRPC starts a server with the standard server interceptors, i.e. basic
+// context, timeout, authentication and access log, then calls register to
+// register the services. Everything is stopped when the test ends.
+func NewGRPC(t testing.TB, c *config.Config, register func(server *grpc.Server)) *GRPC {
+	t.Helper()
+
+	unary := interceptor.NewUnaryInterceptor().
+		WithBasicContext().
+		WithTimeout().
+		WithAuthenticate().
+		WithAccessLog()
+
+	stream := interceptor.NewStreamInterceptor().
+		WithBasicContext().
+		WithTimeout().
+		WithAuthenticate().
+		WithAccessLog()
+
+	return NewGRPCWithOptions(t, register,
+		grpc.UnaryInterceptor(unary.Interceptor(c)),
+		grpc.StreamInterceptor(stream.Interceptor(c)),
+	)
+}
+
+// NewGRPCWithOptions is the same as NewGRPC, but the server is created with
+// the given options only.
+func NewGRPCWithOptions(t testing.TB, register func(server *grpc.Server), opts ...grpc.ServerOption) *GRPC {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(opts...)
+	register(server)
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			t.Logf("grpc server stopped: %v", err)
+		}
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create grpc client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+
+	return &GRPC{Server: server, Conn: conn}
+}
+
+// WithAuthorization returns a context sending the authorization, e.g. from
+// MintAccessToken, with outgoing rpcs.
+func WithAuthorization(ctx context.Context, authorization string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+}
@@ -0,0 +1,73 @@
+package sharedtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/middleware"
+)
+
+// NewHTTPServer starts a server which serves handler behind the standard
+// middleware chain, i.e. context, timeout, session, authentication and access
+// log. It is closed when the test ends.
+func NewHTTPServer(t testing.TB, c *config.Config, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	chain := []func(http.Handler) http.Handler{
+		middleware.SetupContext(c),
+		middleware.Timeout(c),
+		middleware.WithSessionStore(c),
+		middleware.AuthenticationBy(c.Authenticator),
+		middleware.AccessLog(c),
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i](handler)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Do sends a request to the server. If body is not nil, it is encoded as JSON.
+// If authorization is not empty, it is sent as the Authorization header.
+func Do(t testing.TB, server *httptest.Server, method, path string, body any, authorization string) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
@@ -0,0 +1,91 @@
+package sharedtest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/todennus/shared/authn"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/shared/sharedtest"
+	"github.com/todennus/x/xerror"
+	"github.com/xybor-x/snowflake"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const userID snowflake.ID = 42
+
+func TestHTTPServer(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+
+	handler := middleware.RequireAuthentication(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		principal := authn.RequestPrincipal(ctx)
+		response.Write(ctx, w, http.StatusOK, response.NewRESTResponse(principal.UserID.String()))
+	})
+
+	server := sharedtest.NewHTTPServer(t, c, handler)
+
+	var got string
+	resp := sharedtest.Do(t, server, http.MethodGet, "/", nil, sharedtest.MintAccessToken(t, c, userID))
+	sharedtest.AssertRESTSuccess(t, resp, http.StatusOK, &got)
+	if got != userID.String() {
+		t.Errorf("user id = %q, want %q", got, userID.String())
+	}
+
+	resp = sharedtest.Do(t, server, http.MethodGet, "/", nil, "")
+	sharedtest.AssertRESTError(t, resp, http.StatusUnauthorized, nil)
+}
+
+// whoAmI is a service returning the user id of the principal, registered
+// without generated code.
+var whoAmI = grpc.ServiceDesc{
+	ServiceName: "sharedtest.WhoAmI",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Get",
+		Handler: func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			if err := dec(&emptypb.Empty{}); err != nil {
+				return nil, err
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/sharedtest.WhoAmI/Get"}
+			return interceptor(ctx, &emptypb.Empty{}, info, func(ctx context.Context, _ any) (any, error) {
+				var resp *wrapperspb.StringValue
+				var err error
+				if principal := authn.RequestPrincipal(ctx); principal != nil {
+					resp = wrapperspb.String(principal.UserID.String())
+				} else {
+					err = xerror.Enrich(errordef.ErrUnauthenticated, "require authentication to access api")
+				}
+
+				return response.NewResponseHandler(ctx, resp, err).Finalize(ctx)
+			})
+		},
+	}},
+}
+
+func TestGRPCServer(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	server := sharedtest.NewGRPC(t, c, func(server *grpc.Server) {
+		server.RegisterService(&whoAmI, struct{}{})
+	})
+
+	ctx := sharedtest.WithAuthorization(context.Background(), sharedtest.MintAccessToken(t, c, userID))
+	got := &wrapperspb.StringValue{}
+	if err := server.Conn.Invoke(ctx, "/sharedtest.WhoAmI/Get", &emptypb.Empty{}, got); err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+
+	if got.GetValue() != userID.String() {
+		t.Errorf("user id = %q, want %q", got.GetValue(), userID.String())
+	}
+
+	err := server.Conn.Invoke(context.Background(), "/sharedtest.WhoAmI/Get", &emptypb.Empty{}, got)
+	sharedtest.AssertGRPCStatus(t, err, codes.Unauthenticated, errordef.ErrUnauthenticated)
+}
@@ -0,0 +1,51 @@
+package sharedtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/config"
+	"github.com/todennus/shared/tokendef"
+	"github.com/xybor-x/snowflake"
+)
+
+// MintAccessToken issues an access token of a user with the token engine of
+// the config. It returns the value of the authorization header, e.g.
+// "Bearer <token>".
+func MintAccessToken(t testing.TB, c *config.Config, userID snowflake.ID, scopes ...string) string {
+	t.Helper()
+	return mintAccessToken(t, c, userID.String(), "", scopes)
+}
+
+// MintClientToken issues an access token of a client on its own behalf, as
+// obtained with the client_credentials grant.
+func MintClientToken(t testing.TB, c *config.Config, clientID string, scopes ...string) string {
+	t.Helper()
+	return mintAccessToken(t, c, tokendef.ClientSubject(clientID), clientID, scopes)
+}
+
+func mintAccessToken(t testing.TB, c *config.Config, subject, clientID string, scopes []string) string {
+	t.Helper()
+
+	expiration := time.Duration(c.Variable.Authentication.AccessTokenExpiration) * time.Second
+	accessToken := &tokendef.OAuth2AccessToken{
+		OAuth2StandardClaims: &tokendef.OAuth2StandardClaims{
+			ID:        c.NewSnowflakeNode().Generate().String(),
+			Issuer:    c.Variable.Authentication.TokenIssuer,
+			Subject:   subject,
+			ExpiresAt: int(time.Now().Add(expiration).Unix()),
+		},
+		Scope:    strings.Join(scopes, " "),
+		ClientID: clientID,
+		AuthTime: int(time.Now().Unix()),
+	}
+
+	token, err := c.TokenEngine.Generate(context.Background(), accessToken)
+	if err != nil {
+		t.Fatalf("failed to mint access token: %v", err)
+	}
+
+	return c.TokenEngine.Type() + " " + token
+}
@@ -2,6 +2,7 @@ package tokendef
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/todennus/x/token"
@@ -10,6 +11,17 @@ import (
 
 var _ (token.Claims) = (*OAuth2StandardClaims)(nil)
 
+// ClientSubjectPrefix marks the subject of tokens which are issued to a client
+// on its own behalf, e.g. by the client_credentials grant. The subject of the
+// other tokens is the snowflake id of a user.
+const ClientSubjectPrefix = "client:"
+
+// ClientSubject returns the subject of a token issued to a client on its own
+// behalf.
+func ClientSubject(clientID string) string {
+	return ClientSubjectPrefix + clientID
+}
+
 type OAuth2StandardClaims struct {
 	ID        string `json:"jti,omitempty"`
 	Issuer    string `json:"iss,omitempty"`
@@ -27,14 +39,32 @@ func (claims *OAuth2StandardClaims) SnowflakeID() snowflake.ID {
 	return id
 }
 
+// SnowflakeSub returns the user id of the subject. It panics if the subject is
+// not a user, use ParseSnowflakeSub if the token may belong to a client.
 func (claims *OAuth2StandardClaims) SnowflakeSub() snowflake.ID {
-	id, err := snowflake.ParseString(claims.Subject)
+	id, err := claims.ParseSnowflakeSub()
 	if err != nil {
 		panic(err)
 	}
 	return id
 }
 
+// ParseSnowflakeSub returns the user id of the subject.
+func (claims *OAuth2StandardClaims) ParseSnowflakeSub() (snowflake.ID, error) {
+	return snowflake.ParseString(claims.Subject)
+}
+
+// ClientSub returns the client id of the subject. The second value is false if
+// the subject is not a client.
+func (claims *OAuth2StandardClaims) ClientSub() (string, bool) {
+	clientID, found := strings.CutPrefix(claims.Subject, ClientSubjectPrefix)
+	if !found || clientID == "" {
+		return "", false
+	}
+
+	return clientID, true
+}
+
 func (claims *OAuth2StandardClaims) Valid() error {
 	now := time.Now()
 	if claims.ExpiresAt != 0 && time.Unix(int64(claims.ExpiresAt), 0).Before(now) {
@@ -50,8 +80,10 @@ func (claims *OAuth2StandardClaims) Valid() error {
 		return fmt.Errorf("%w: %s", token.ErrTokenInvalidFormat, "invalid jti")
 	}
 
-	if _, err := snowflake.ParseString(claims.ID); err != nil {
-		return fmt.Errorf("%w: %s", token.ErrTokenInvalidFormat, "invalid sub")
+	if _, isClient := claims.ClientSub(); !isClient {
+		if _, err := claims.ParseSnowflakeSub(); err != nil {
+			return fmt.Errorf("%w: %s", token.ErrTokenInvalidFormat, "invalid sub")
+		}
 	}
 
 	createdAt := time.UnixMilli(snowflakeID.Time())
@@ -65,6 +97,24 @@ func (claims *OAuth2StandardClaims) Valid() error {
 type OAuth2AccessToken struct {
 	*OAuth2StandardClaims
 	Scope string `json:"scope"`
+
+	// ClientID is the client which the token is issued to (RFC 9068). Tokens
+	// of OpenID Connect issuers may carry it in AuthorizedParty instead.
+	ClientID        string `json:"client_id,omitempty"`
+	AuthorizedParty string `json:"azp,omitempty"`
+
+	// AuthTime is the time when the user authenticated (in unix second).
+	AuthTime int `json:"auth_time,omitempty"`
+}
+
+// Client returns the client which the token is issued to, or an empty string
+// if the token does not mention it.
+func (token *OAuth2AccessToken) Client() string {
+	if token.ClientID != "" {
+		return token.ClientID
+	}
+
+	return token.AuthorizedParty
 }
 
 type OAuth2RefreshToken struct {
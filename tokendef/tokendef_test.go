@@ -0,0 +1,70 @@
+package tokendef_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/tokendef"
+	"github.com/todennus/x/token"
+	"github.com/xybor-x/snowflake"
+)
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name  string
+		token tokendef.OAuth2AccessToken
+		want  string
+	}{
+		{name: "client_id", token: tokendef.OAuth2AccessToken{ClientID: "client", AuthorizedParty: "azp"}, want: "client"},
+		{name: "azp", token: tokendef.OAuth2AccessToken{AuthorizedParty: "azp"}, want: "azp"},
+		{name: "none", token: tokendef.OAuth2AccessToken{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.Client(); got != tt.want {
+				t.Errorf("Client() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimsValid(t *testing.T) {
+	node, err := snowflake.NewNode(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jti := node.Generate().String()
+	hour := int(time.Hour.Seconds())
+	now := int(time.Now().Unix())
+
+	tests := []struct {
+		name       string
+		claims     tokendef.OAuth2StandardClaims
+		wantErr    error
+		wantClient string
+	}{
+		{name: "user", claims: tokendef.OAuth2StandardClaims{ID: jti, Subject: "42", ExpiresAt: now + hour}},
+		{name: "client", claims: tokendef.OAuth2StandardClaims{ID: jti, Subject: tokendef.ClientSubject("app")}, wantClient: "app"},
+		{name: "expired", claims: tokendef.OAuth2StandardClaims{ID: jti, Subject: "42", ExpiresAt: now - hour}, wantErr: token.ErrTokenExpired},
+		{name: "not yet valid", claims: tokendef.OAuth2StandardClaims{ID: jti, Subject: "42", NotBefore: now + hour}, wantErr: token.ErrTokenNotYetValid},
+		{name: "invalid jti", claims: tokendef.OAuth2StandardClaims{ID: "jti", Subject: "42"}, wantErr: token.ErrTokenInvalidFormat},
+		{name: "invalid sub", claims: tokendef.OAuth2StandardClaims{ID: jti, Subject: "alice"}, wantErr: token.ErrTokenInvalidFormat},
+		{name: "empty client sub", claims: tokendef.OAuth2StandardClaims{ID: jti, Subject: tokendef.ClientSubject("")}, wantErr: token.ErrTokenInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.claims.Valid(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Valid() = %v, want %v", err, tt.wantErr)
+			}
+
+			clientID, isClient := tt.claims.ClientSub()
+			if clientID != tt.wantClient || isClient != (tt.wantClient != "") {
+				t.Errorf("ClientSub() = %q, %v, want %q", clientID, isClient, tt.wantClient)
+			}
+		})
+	}
+}
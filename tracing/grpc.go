@@ -0,0 +1,218 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var _ propagation.TextMapCarrier = (*MetadataCarrier)(nil)
+
+// MetadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type MetadataCarrier metadata.MD
+
+func (c MetadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (c MetadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// StartGRPCServerSpan extracts the trace context from the incoming metadata
+// and starts a server span for the rpc.
+func StartGRPCServerSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = Propagator.Extract(ctx, MetadataCarrier(md))
+	}
+
+	return Tracer().Start(ctx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(rpcAttributes(fullMethod)...),
+	)
+}
+
+// EndGRPCSpan records the status of the rpc and ends a client span. Any error
+// marks the span as failed.
+func EndGRPCSpan(span trace.Span, err error) {
+	st, _ := status.FromError(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(st.Code())))
+	if err != nil {
+		span.SetStatus(codes.Error, st.Message())
+	}
+
+	span.End()
+}
+
+// EndGRPCServerSpan records the status of the rpc and ends a server span.
+// Following the semantic conventions, only the codes caused by the server
+// mark the span as failed, the other ones are errors of the client.
+func EndGRPCServerSpan(span trace.Span, err error) {
+	st, _ := status.FromError(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(st.Code())))
+	if isServerError(st.Code()) {
+		span.SetStatus(codes.Error, st.Message())
+	}
+
+	span.End()
+}
+
+func isServerError(code grpccodes.Code) bool {
+	switch code {
+	case grpccodes.Unknown,
+		grpccodes.DeadlineExceeded,
+		grpccodes.Unimplemented,
+		grpccodes.Internal,
+		grpccodes.Unavailable,
+		grpccodes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// UnaryClientInterceptor starts a client span for each outgoing rpc and
+// injects its trace context into the outgoing metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, span := startGRPCClientSpan(ctx, method)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		EndGRPCSpan(span, err)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor is the streaming counterpart of
+// UnaryClientInterceptor. The span ends when the stream is finished.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, span := startGRPCClientSpan(ctx, method)
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			EndGRPCSpan(span, err)
+			return nil, err
+		}
+
+		return newClientStream(ctx, stream, span), nil
+	}
+}
+
+func startGRPCClientSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(rpcAttributes(fullMethod)...),
+	)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	Propagator.Inject(ctx, MetadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+func rpcAttributes(fullMethod string) []attribute.KeyValue {
+	service, method := splitMethod(fullMethod)
+	return []attribute.KeyValue{
+		semconv.RPCSystemGRPC,
+		semconv.RPCService(service),
+		semconv.RPCMethod(method),
+	}
+}
+
+// clientStream ends the span when the stream fails or is finished. The span
+// also ends when the context of the stream is done, e.g. if the caller stops
+// reading the stream before the end.
+type clientStream struct {
+	grpc.ClientStream
+
+	ctx  context.Context
+	span trace.Span
+	once sync.Once
+}
+
+func newClientStream(ctx context.Context, stream grpc.ClientStream, span trace.Span) *clientStream {
+	s := &clientStream{ClientStream: stream, ctx: ctx, span: span}
+	context.AfterFunc(stream.Context(), func() {
+		s.end(status.FromContextError(s.ctx.Err()).Err())
+	})
+
+	return s
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.end(err)
+	}
+
+	return err
+}
+
+func (s *clientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	if err != nil {
+		s.end(err)
+	}
+
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.end(nil)
+	} else if err != nil {
+		s.end(err)
+	}
+
+	return err
+}
+
+func (s *clientStream) end(err error) {
+	s.once.Do(func() { EndGRPCSpan(s.span, err) })
+}
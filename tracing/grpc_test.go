@@ -0,0 +1,154 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/todennus/shared/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const fullMethod = "/todennus.user.v1.UserService/GetUser"
+
+func TestGRPCPropagation(t *testing.T) {
+	exporter := record(t)
+
+	// The invoker plays the server: it receives the outgoing metadata of the
+	// client as incoming metadata.
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		serverCtx := metadata.NewIncomingContext(context.Background(), md)
+
+		_, span := tracing.StartGRPCServerSpan(serverCtx, method)
+		err := status.Error(grpccodes.NotFound, "not_found:user not found")
+		tracing.EndGRPCServerSpan(span, err)
+		return err
+	}
+
+	err := tracing.UnaryClientInterceptor()(context.Background(), fullMethod, nil, nil, nil, invoker)
+	if status.Code(err) != grpccodes.NotFound {
+		t.Fatalf("code = %v, want NotFound", status.Code(err))
+	}
+
+	var serverSpan, clientSpan tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		switch span.SpanKind {
+		case trace.SpanKindServer:
+			serverSpan = span
+		case trace.SpanKindClient:
+			clientSpan = span
+		}
+	}
+
+	if serverSpan.Name == "" || clientSpan.Name == "" {
+		t.Fatalf("spans = %v, want a client and a server span", exporter.GetSpans().Snapshots())
+	}
+
+	if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() {
+		t.Errorf("server span parent = %s, want the client span %s", serverSpan.Parent.SpanID(), clientSpan.SpanContext.SpanID())
+	}
+
+	if serverSpan.Name != fullMethod {
+		t.Errorf("server span name = %q, want %q", serverSpan.Name, fullMethod)
+	}
+
+	for key, want := range map[attribute.Key]string{
+		semconv.RPCSystemKey:  "grpc",
+		semconv.RPCServiceKey: "todennus.user.v1.UserService",
+		semconv.RPCMethodKey:  "GetUser",
+	} {
+		if got, _ := attributeOf(serverSpan, key); got.AsString() != want {
+			t.Errorf("%s = %q, want %q", key, got.AsString(), want)
+		}
+	}
+
+	// NotFound is caused by the client, it fails the client span only.
+	if serverSpan.Status.Code != codes.Unset {
+		t.Errorf("server status = %v, want unset", serverSpan.Status.Code)
+	}
+
+	if clientSpan.Status.Code != codes.Error {
+		t.Errorf("client status = %v, want error", clientSpan.Status.Code)
+	}
+}
+
+func TestEndGRPCServerSpan(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "success", err: nil, want: codes.Unset},
+		{name: "invalid argument", err: status.Error(grpccodes.InvalidArgument, "invalid_request"), want: codes.Unset},
+		{name: "unauthenticated", err: status.Error(grpccodes.Unauthenticated, "unauthenticated"), want: codes.Unset},
+		{name: "internal", err: status.Error(grpccodes.Internal, "server_error"), want: codes.Error},
+		{name: "unavailable", err: status.Error(grpccodes.Unavailable, "server_overloaded"), want: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := record(t)
+
+			_, span := tracing.StartGRPCServerSpan(context.Background(), fullMethod)
+			tracing.EndGRPCServerSpan(span, tt.err)
+
+			recorded := findSpan(t, exporter, fullMethod)
+			if recorded.Status.Code != tt.want {
+				t.Errorf("status = %v, want %v", recorded.Status.Code, tt.want)
+			}
+
+			if value, _ := attributeOf(recorded, semconv.RPCGRPCStatusCodeKey); value.AsInt64() != int64(status.Code(tt.err)) {
+				t.Errorf("grpc status code = %d, want %d", value.AsInt64(), status.Code(tt.err))
+			}
+		})
+	}
+}
+
+// idleStream is a stream which is never read, its context is done when the
+// rpc is canceled.
+type idleStream struct {
+	grpc.ClientStream
+	ctx context.Context
+}
+
+func (s *idleStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamClientSpanEndsWithContext(t *testing.T) {
+	exporter := record(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &idleStream{ctx: ctx}, nil
+	}
+
+	if _, err := tracing.StreamClientInterceptor()(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, fullMethod, streamer); err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("spans = %v, want none before the stream is done", spans.Snapshots())
+	}
+
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for len(exporter.GetSpans()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	recorded := findSpan(t, exporter, fullMethod)
+	if value, _ := attributeOf(recorded, semconv.RPCGRPCStatusCodeKey); value.AsInt64() != int64(grpccodes.Canceled) {
+		t.Errorf("grpc status code = %d, want %d", value.AsInt64(), grpccodes.Canceled)
+	}
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartHTTPServerSpan extracts the trace context from the request headers and
+// starts a server span for the request. The span name is completed with the
+// route by EndHTTPServerSpan, once the request has been routed.
+func StartHTTPServerSpan(r *http.Request) (context.Context, trace.Span) {
+	ctx := Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	return Tracer().Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method)),
+	)
+}
+
+// EndHTTPServerSpan records the matched route and the response status code,
+// then ends the span.
+func EndHTTPServerSpan(span trace.Span, method string, route string, status int) {
+	if route != "" {
+		span.SetName(fmt.Sprintf("%s %s", method, route))
+		span.SetAttributes(semconv.HTTPRoute(route))
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+
+	span.End()
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// Transport starts a client span for each outgoing request and injects its
+// trace context into the request headers.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps base, or http.DefaultTransport if base is nil.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{base: base}
+}
+
+// redactedURL returns the scheme, host and path of u. The query and the user
+// info are not recorded, they can hold tokens or authorization codes.
+func redactedURL(u *url.URL) string {
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := Tracer().Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLFull(redactedURL(r.URL)),
+		),
+	)
+	defer span.End()
+
+	r = r.Clone(ctx)
+	Propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+
+	return resp, nil
+}
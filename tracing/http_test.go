@@ -0,0 +1,90 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/todennus/shared/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPServerSpan(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   codes.Code
+	}{
+		{name: "success", status: http.StatusOK, want: codes.Unset},
+		{name: "client error", status: http.StatusNotFound, want: codes.Unset},
+		{name: "server error", status: http.StatusInternalServerError, want: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := record(t)
+
+			r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+			_, span := tracing.StartHTTPServerSpan(r)
+			tracing.EndHTTPServerSpan(span, http.MethodGet, "/users/{id}", tt.status)
+
+			recorded := findSpan(t, exporter, "GET /users/{id}")
+			if recorded.SpanKind != trace.SpanKindServer {
+				t.Errorf("kind = %v, want server", recorded.SpanKind)
+			}
+
+			if got := recorded.Parent.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("trace id = %s, want the one of traceparent", got)
+			}
+
+			if value, _ := attributeOf(recorded, semconv.HTTPRouteKey); value.AsString() != "/users/{id}" {
+				t.Errorf("route = %q, want /users/{id}", value.AsString())
+			}
+
+			if value, _ := attributeOf(recorded, semconv.HTTPResponseStatusCodeKey); value.AsInt64() != int64(tt.status) {
+				t.Errorf("status code = %d, want %d", value.AsInt64(), tt.status)
+			}
+
+			if recorded.Status.Code != tt.want {
+				t.Errorf("status = %v, want %v", recorded.Status.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportPropagation(t *testing.T) {
+	exporter := record(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.StartHTTPServerSpan(r)
+		tracing.EndHTTPServerSpan(span, r.Method, "/resource", http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: tracing.NewTransport(nil)}
+	resp, err := client.Get(server.URL + "/resource?code=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	serverSpan := findSpan(t, exporter, "GET /resource")
+	clientSpan := findSpan(t, exporter, "GET")
+
+	if clientSpan.SpanKind != trace.SpanKindClient {
+		t.Errorf("client kind = %v, want client", clientSpan.SpanKind)
+	}
+
+	if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() {
+		t.Errorf("server span parent = %s, want the client span %s", serverSpan.Parent.SpanID(), clientSpan.SpanContext.SpanID())
+	}
+
+	// The query is not recorded, it can hold secrets.
+	if got, _ := attributeOf(clientSpan, semconv.URLFullKey); got.AsString() != server.URL+"/resource" {
+		t.Errorf("url = %q, want %q", got.AsString(), server.URL+"/resource")
+	}
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/todennus/x/xcontext"
+	"github.com/xybor-x/snowflake"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const InstrumentationName = "github.com/todennus/shared"
+
+const (
+	ErrorCodeKey = attribute.Key("todennus.error.code")
+	ClientIDKey  = attribute.Key("todennus.client.id")
+)
+
+// Propagator extracts and injects the W3C trace context and baggage.
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// Tracer returns the tracer of the global TracerProvider. When no provider is
+// registered, spans are not recorded but the remote trace context is still
+// propagated.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// WithLogger adds the trace id and span id of the current span to the logger
+// in the context.
+func WithLogger(ctx context.Context) context.Context {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ctx
+	}
+
+	logger := xcontext.Logger(ctx).With(
+		"trace_id", spanContext.TraceID().String(),
+		"span_id", spanContext.SpanID().String(),
+	)
+
+	return xcontext.WithLogger(ctx, logger)
+}
+
+// SetUserID attaches the authenticated user to the current span.
+func SetUserID(ctx context.Context, userID snowflake.ID) {
+	trace.SpanFromContext(ctx).SetAttributes(semconv.EnduserID(userID.String()))
+}
+
+// SetClientID attaches the OAuth2 client of the request to the current span.
+func SetClientID(ctx context.Context, clientID string) {
+	trace.SpanFromContext(ctx).SetAttributes(ClientIDKey.String(clientID))
+}
+
+// SetErrorCode attaches an errordef code to the current span. The span status
+// is set when the span ends, from the HTTP status or the gRPC code, so that
+// client errors do not mark server spans as failed.
+func SetErrorCode(ctx context.Context, code string) {
+	trace.SpanFromContext(ctx).SetAttributes(ErrorCodeKey.String(code))
+}
+
+// splitMethod splits a gRPC full method, e.g. /package.Service/Method, into
+// the service and method name.
+func splitMethod(fullMethod string) (string, string) {
+	service, method, found := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !found {
+		return "", service
+	}
+
+	return service, method
+}
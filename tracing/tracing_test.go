@@ -0,0 +1,69 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/todennus/shared/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record registers an in-memory exporter as the global tracer provider for
+// the duration of the test.
+func record(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return exporter
+}
+
+// findSpan returns the recorded span of the given name.
+func findSpan(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	t.Fatalf("span %q is not recorded, got %v", name, exporter.GetSpans().Snapshots())
+	return tracetest.SpanStub{}
+}
+
+func attributeOf(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestSetErrorCode(t *testing.T) {
+	exporter := record(t)
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "operation")
+	tracing.SetErrorCode(ctx, "not_found")
+	span.End()
+
+	recorded := findSpan(t, exporter, "operation")
+	if value, ok := attributeOf(recorded, tracing.ErrorCodeKey); !ok || value.AsString() != "not_found" {
+		t.Errorf("error code = %v, want not_found", value.Emit())
+	}
+
+	if recorded.Status.Code != codes.Unset {
+		t.Errorf("status = %v, want unset", recorded.Status.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/x/xerror"
+)
+
+// DecodeJSON decodes the JSON body of a request and validates it with the
+// Default validator. The returned error can be passed to
+// response.RESTWriteLogInvalidRequestError.
+func DecodeJSON[T any](r *http.Request) (T, error) {
+	var req T
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, decodeError(err)
+	}
+
+	return req, Default.Validate(r.Context(), &req)
+}
+
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return err
+	case errors.Is(err, io.EOF):
+		return xerror.Enrich(errordef.ErrRequestInvalid, "request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return xerror.Enrich(errordef.ErrRequestInvalid, "request body is not a valid json")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return errordef.NewViolationError(errordef.FieldViolation{
+			Field:       typeErr.Field,
+			Description: "must be of type " + typeErr.Type.String(),
+		})
+	default:
+		return xerror.Enrich(errordef.ErrRequestInvalid, "%s", err.Error())
+	}
+}
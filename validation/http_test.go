@@ -0,0 +1,85 @@
+package validation_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/middleware"
+	"github.com/todennus/shared/response"
+	"github.com/todennus/shared/sharedtest"
+	"github.com/todennus/shared/validation"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	c := sharedtest.NewConfig(t)
+	c.Variable.Server.MaxBodySize = 128
+
+	handler := middleware.SetupContext(c)(middleware.NewBodyLimit().Middleware(c)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, err := validation.DecodeJSON[profile](r)
+			if err != nil {
+				response.RESTWriteLogInvalidRequestError(r.Context(), w, err)
+				return
+			}
+
+			response.Write(r.Context(), w, http.StatusOK, response.NewRESTResponse(req.Name))
+		}),
+	))
+
+	tests := []struct {
+		name           string
+		body           string
+		want           int
+		wantErr        error
+		wantViolations []response.RESTFieldViolation
+	}{
+		{name: "valid", body: `{"name":"Alice"}`, want: http.StatusOK},
+		{name: "empty", body: ``, want: http.StatusBadRequest, wantErr: errordef.ErrRequestInvalid},
+		{name: "syntax", body: `{"name":`, want: http.StatusBadRequest, wantErr: errordef.ErrRequestInvalid},
+		{
+			name:           "type",
+			body:           `{"name":1}`,
+			want:           http.StatusBadRequest,
+			wantErr:        errordef.ErrRequestInvalid,
+			wantViolations: []response.RESTFieldViolation{{Field: "name", Description: "must be of type string"}},
+		},
+		{
+			name:           "validation",
+			body:           `{"name":""}`,
+			want:           http.StatusBadRequest,
+			wantErr:        errordef.ErrRequestInvalid,
+			wantViolations: []response.RESTFieldViolation{{Field: "name", Description: "is required"}},
+		},
+		{name: "too large", body: `{"name":"` + strings.Repeat("a", 128) + `"}`, want: http.StatusRequestEntityTooLarge, wantErr: errordef.ErrRequestTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.ContentLength = -1 // Bounded while being decoded.
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tt.wantErr == nil {
+				var name string
+				sharedtest.AssertRESTSuccess(t, w.Result(), tt.want, &name)
+				return
+			}
+
+			resp := sharedtest.AssertRESTError(t, w.Result(), tt.want, tt.wantErr)
+			if len(resp.Violations) != len(tt.wantViolations) {
+				t.Fatalf("violations = %v, want %v", resp.Violations, tt.wantViolations)
+			}
+
+			for i := range tt.wantViolations {
+				if resp.Violations[i] != tt.wantViolations[i] {
+					t.Errorf("violations[%d] = %v, want %v", i, resp.Violations[i], tt.wantViolations[i])
+				}
+			}
+		})
+	}
+}
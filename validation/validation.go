@@ -0,0 +1,187 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/x/xerror"
+)
+
+// Validator checks a request. It returns an errordef.ViolationError if some
+// fields are invalid.
+type Validator interface {
+	Validate(ctx context.Context, req any) error
+}
+
+// Validatable is implemented by requests which validate themselves, e.g.
+// protobuf messages with generated Validate methods.
+type Validatable interface {
+	Validate() error
+}
+
+// Default is the validator used when no validator is given.
+var Default Validator = NewStructValidator()
+
+var _ Validator = (*StructValidator)(nil)
+
+// StructValidator calls the Validate method of Validatable requests, then
+// checks the `validate` struct tags of struct requests. Violations are named
+// after the json tag of the field.
+type StructValidator struct {
+	validate *validator.Validate
+}
+
+func NewStructValidator() *StructValidator {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(jsonName)
+
+	return &StructValidator{validate: validate}
+}
+
+// Engine returns the underlying validator, e.g. to register custom tags.
+func (v *StructValidator) Engine() *validator.Validate {
+	return v.validate
+}
+
+func (v *StructValidator) Validate(ctx context.Context, req any) error {
+	if validatable, ok := req.(Validatable); ok {
+		if err := validatable.Validate(); err != nil {
+			return FromError(err)
+		}
+	}
+
+	if !isStruct(req) {
+		return nil
+	}
+
+	err := v.validate.StructCtx(ctx, req)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return xerror.Enrich(errordef.ErrRequestInvalid, "%s", err.Error())
+	}
+
+	violations := make([]errordef.FieldViolation, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		violations = append(violations, errordef.FieldViolation{
+			Field:       fieldPath(fieldErr.Namespace()),
+			Description: describe(fieldErr),
+		})
+	}
+
+	return errordef.NewViolationError(violations...)
+}
+
+// FromError converts the error of a Validate method into an ErrRequestInvalid.
+// Errors exposing Field and Reason methods, as generated by
+// protoc-gen-validate, become field violations.
+func FromError(err error) error {
+	var violationErr *errordef.ViolationError
+	if errors.As(err, &violationErr) {
+		return err
+	}
+
+	var errs []error
+	if multi, ok := err.(interface{ AllErrors() []error }); ok {
+		errs = multi.AllErrors()
+	} else {
+		errs = []error{err}
+	}
+
+	violations := []errordef.FieldViolation{}
+	for _, err := range errs {
+		fieldErr, ok := err.(interface {
+			Field() string
+			Reason() string
+		})
+		if !ok {
+			return xerror.Enrich(errordef.ErrRequestInvalid, "%s", err.Error())
+		}
+
+		violations = append(violations, errordef.FieldViolation{Field: fieldErr.Field(), Description: fieldErr.Reason()})
+	}
+
+	return errordef.NewViolationError(violations...)
+}
+
+func isStruct(req any) bool {
+	t := reflect.TypeOf(req)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t != nil && t.Kind() == reflect.Struct
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+// fieldPath removes the name of the root struct from a namespace, e.g.
+// CreateUserRequest.profile.name becomes profile.name.
+func fieldPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+
+	return path
+}
+
+func describe(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fieldErr.Param() + unit(fieldErr)
+	case "max":
+		return "must be at most " + fieldErr.Param() + unit(fieldErr)
+	case "len":
+		return "must have exactly " + fieldErr.Param() + unit(fieldErr)
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "gte":
+		return "must be greater than or equal to " + fieldErr.Param()
+	case "lt":
+		return "must be less than " + fieldErr.Param()
+	case "lte":
+		return "must be less than or equal to " + fieldErr.Param()
+	case "oneof":
+		return "must be one of " + fieldErr.Param()
+	case "email":
+		return "must be a valid email"
+	case "url":
+		return "must be a valid url"
+	default:
+		if fieldErr.Param() != "" {
+			return "must satisfy " + fieldErr.Tag() + "=" + fieldErr.Param()
+		}
+		return "must satisfy " + fieldErr.Tag()
+	}
+}
+
+func unit(fieldErr validator.FieldError) string {
+	switch fieldErr.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
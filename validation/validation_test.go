@@ -0,0 +1,183 @@
+package validation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/todennus/shared/errordef"
+	"github.com/todennus/shared/validation"
+)
+
+type profile struct {
+	Name string `json:"name" validate:"required,max=5"`
+}
+
+type createUserRequest struct {
+	Username string   `json:"username" validate:"required,min=3"`
+	Role     string   `json:"role,omitempty" validate:"omitempty,oneof=admin user"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Age      int      `validate:"gte=0"`
+	Profile  profile  `json:"profile"`
+	Secret   string   `json:"-" validate:"required"`
+}
+
+func validRequest() createUserRequest {
+	return createUserRequest{Username: "alice", Profile: profile{Name: "Alice"}, Secret: "secret"}
+}
+
+func TestStructValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(req *createUserRequest)
+		want   []errordef.FieldViolation
+	}{
+		{name: "valid", modify: func(req *createUserRequest) {}},
+		{
+			name:   "required",
+			modify: func(req *createUserRequest) { req.Username = "" },
+			want:   []errordef.FieldViolation{{Field: "username", Description: "is required"}},
+		},
+		{
+			name:   "min string",
+			modify: func(req *createUserRequest) { req.Username = "al" },
+			want:   []errordef.FieldViolation{{Field: "username", Description: "must be at least 3 characters"}},
+		},
+		{
+			name:   "max slice",
+			modify: func(req *createUserRequest) { req.Tags = []string{"a", "b", "c"} },
+			want:   []errordef.FieldViolation{{Field: "tags", Description: "must be at most 2 items"}},
+		},
+		{
+			name:   "oneof",
+			modify: func(req *createUserRequest) { req.Role = "root" },
+			want:   []errordef.FieldViolation{{Field: "role", Description: "must be one of admin user"}},
+		},
+		{
+			name:   "without json tag",
+			modify: func(req *createUserRequest) { req.Age = -1 },
+			want:   []errordef.FieldViolation{{Field: "Age", Description: "must be greater than or equal to 0"}},
+		},
+		{
+			name:   "nested",
+			modify: func(req *createUserRequest) { req.Profile.Name = "Alexander" },
+			want:   []errordef.FieldViolation{{Field: "profile.name", Description: "must be at most 5 characters"}},
+		},
+		{
+			name: "several",
+			modify: func(req *createUserRequest) {
+				req.Username = ""
+				req.Profile.Name = ""
+			},
+			want: []errordef.FieldViolation{
+				{Field: "username", Description: "is required"},
+				{Field: "profile.name", Description: "is required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validRequest()
+			tt.modify(&req)
+
+			assertViolations(t, validation.NewStructValidator().Validate(context.Background(), &req), tt.want)
+		})
+	}
+}
+
+type fieldError struct {
+	field, reason string
+}
+
+func (e fieldError) Error() string  { return e.field + ": " + e.reason }
+func (e fieldError) Field() string  { return e.field }
+func (e fieldError) Reason() string { return e.reason }
+
+type multiError []error
+
+func (e multiError) Error() string      { return "several errors" }
+func (e multiError) AllErrors() []error { return e }
+
+// validatable validates itself, as protobuf messages generated by
+// protoc-gen-validate.
+type validatable struct {
+	err error
+}
+
+func (v validatable) Validate() error {
+	return v.err
+}
+
+func TestValidatable(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		want    []errordef.FieldViolation
+		wantErr error
+	}{
+		{name: "valid"},
+		{
+			name: "field error",
+			err:  fieldError{field: "id", reason: "is required"},
+			want: []errordef.FieldViolation{{Field: "id", Description: "is required"}},
+		},
+		{
+			name: "all errors",
+			err:  multiError{fieldError{field: "id", reason: "is required"}, fieldError{field: "name", reason: "is too long"}},
+			want: []errordef.FieldViolation{{Field: "id", Description: "is required"}, {Field: "name", Description: "is too long"}},
+		},
+		{name: "plain error", err: errors.New("invalid"), wantErr: errordef.ErrRequestInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validation.Default.Validate(context.Background(), validatable{err: tt.err})
+			if tt.wantErr != nil {
+				var violationErr *errordef.ViolationError
+				if !errors.Is(err, tt.wantErr) || errors.As(err, &violationErr) {
+					t.Errorf("err = %v, want %v without violations", err, tt.wantErr)
+				}
+				return
+			}
+
+			assertViolations(t, err, tt.want)
+		})
+	}
+}
+
+func TestValidateNotStruct(t *testing.T) {
+	if err := validation.Default.Validate(context.Background(), "request"); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+func assertViolations(t *testing.T, err error, want []errordef.FieldViolation) {
+	t.Helper()
+
+	if len(want) == 0 {
+		if err != nil {
+			t.Errorf("err = %v, want nil", err)
+		}
+		return
+	}
+
+	if !errors.Is(err, errordef.ErrRequestInvalid) {
+		t.Fatalf("err = %v, want %v", err, errordef.ErrRequestInvalid)
+	}
+
+	var violationErr *errordef.ViolationError
+	if !errors.As(err, &violationErr) {
+		t.Fatalf("err = %v, want a ViolationError", err)
+	}
+
+	if len(violationErr.Violations) != len(want) {
+		t.Fatalf("Violations = %v, want %v", violationErr.Violations, want)
+	}
+
+	for i := range want {
+		if violationErr.Violations[i] != want[i] {
+			t.Errorf("Violations[%d] = %v, want %v", i, violationErr.Violations[i], want[i])
+		}
+	}
+}